@@ -24,12 +24,23 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/northwesternmutual/kanalictl/config"
 	"github.com/northwesternmutual/kanalictl/controller"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 func init() {
-	applyCmd.Flags().StringP("file", "f", "", "location to configuration file")
+	applyCmd.Flags().StringArrayP("file", "f", []string{}, "location to configuration file, directory or glob pattern, or - for stdin. May be repeated.")
+	applyCmd.Flags().BoolP(config.FlagRecursive.Long, config.FlagRecursive.Short, config.FlagRecursive.Value.(bool), config.FlagRecursive.Usage)
+	applyCmd.Flags().BoolP(config.FlagUseKubectl.Long, config.FlagUseKubectl.Short, config.FlagUseKubectl.Value.(bool), config.FlagUseKubectl.Usage)
+	applyCmd.Flags().BoolP(config.FlagAtomic.Long, config.FlagAtomic.Short, config.FlagAtomic.Value.(bool), config.FlagAtomic.Usage)
+	applyCmd.Flags().String(config.FlagDryRun.Long, config.FlagDryRun.Value.(string), config.FlagDryRun.Usage)
+	applyCmd.Flags().Lookup(config.FlagDryRun.Long).NoOptDefVal = controller.DryRunClient
+	applyCmd.Flags().BoolP(config.FlagPrune.Long, config.FlagPrune.Short, config.FlagPrune.Value.(bool), config.FlagPrune.Usage)
+	applyCmd.Flags().StringP(config.FlagSelector.Long, config.FlagSelector.Short, config.FlagSelector.Value.(string), config.FlagSelector.Usage)
+	applyCmd.Flags().BoolP(config.FlagPruneDryRun.Long, config.FlagPruneDryRun.Short, config.FlagPruneDryRun.Value.(bool), config.FlagPruneDryRun.Usage)
+	applyCmd.Flags().StringP(config.FlagPruneNamespace.Long, config.FlagPruneNamespace.Short, config.FlagPruneNamespace.Value.(string), config.FlagPruneNamespace.Usage)
 	RootCmd.AddCommand(applyCmd)
 }
 
@@ -37,12 +48,31 @@ var applyCmd = &cobra.Command{
 	Use:   `apply`,
 	Short: `Apply a configuration to a resource by filename.`,
 	Long:  `Apply a configuration to a resource by filename.`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		if err := bindFlags(cmd, config.FlagRecursive, config.FlagUseKubectl, config.FlagAtomic, config.FlagPrune, config.FlagSelector, config.FlagPruneDryRun, config.FlagPruneNamespace); err != nil {
+			fmt.Print(err.Error())
+			os.Exit(1)
+		}
+		if err := bindFlag(cmd, config.FlagDryRun, config.KeyDryRun); err != nil {
+			fmt.Print(err.Error())
+			os.Exit(1)
+		}
+	},
 	Run: func(cmd *cobra.Command, args []string) {
-		path, err := cmd.Flags().GetString("file")
+		inputs, err := cmd.Flags().GetStringArray("file")
 		if err != nil {
 			fmt.Print(err.Error())
 			os.Exit(1)
 		}
-		os.Exit(controller.CreateOrApply("apply", path))
+		os.Exit(controller.CreateOrApply("apply", inputs, controller.Options{
+			UseKubectl:     viper.GetBool(config.FlagUseKubectl.GetLong()),
+			Atomic:         viper.GetBool(config.FlagAtomic.GetLong()),
+			Recursive:      viper.GetBool(config.FlagRecursive.GetLong()),
+			DryRun:         viper.GetString(config.KeyDryRun),
+			Prune:          viper.GetBool(config.FlagPrune.GetLong()),
+			Selector:       viper.GetString(config.FlagSelector.GetLong()),
+			PruneDryRun:    viper.GetBool(config.FlagPruneDryRun.GetLong()),
+			PruneNamespace: viper.GetString(config.FlagPruneNamespace.GetLong()),
+		}))
 	},
 }
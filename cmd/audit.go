@@ -0,0 +1,101 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/northwesternmutual/kanalictl/config"
+	"github.com/northwesternmutual/kanalictl/pkg/audit"
+	"github.com/northwesternmutual/kanalictl/pkg/decrypt"
+	"github.com/northwesternmutual/kanalictl/pkg/generate"
+	"github.com/northwesternmutual/kanalictl/pkg/rsakey"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	auditCmd.Flags().StringP(config.FlagRSAPrivateKeyFile.Long, config.FlagRSAPrivateKeyFile.Short, config.FlagRSAPrivateKeyFile.Value.(string), config.FlagRSAPrivateKeyFile.Usage)
+	auditCmd.Flags().StringP(config.FlagKeyInFile.Long, "f", config.FlagKeyInFile.Value.(string), config.FlagKeyInFile.Usage)
+	auditCmd.Flags().BoolP(config.FlagFromCluster.Long, config.FlagFromCluster.Short, config.FlagFromCluster.Value.(bool), config.FlagFromCluster.Usage)
+	auditCmd.Flags().StringP(config.FlagDecryptNamespace.Long, config.FlagDecryptNamespace.Short, config.FlagDecryptNamespace.Value.(string), config.FlagDecryptNamespace.Usage)
+	auditCmd.Flags().BoolP(config.FlagAllNamespaces.Long, config.FlagAllNamespaces.Short, config.FlagAllNamespaces.Value.(bool), config.FlagAllNamespaces.Usage)
+	auditCmd.Flags().IntP(config.FlagDecryptConcurrency.Long, config.FlagDecryptConcurrency.Short, config.FlagDecryptConcurrency.Value.(int), config.FlagDecryptConcurrency.Usage)
+	auditCmd.Flags().StringP(config.FlagKeyAlphabet.Long, config.FlagKeyAlphabet.Short, config.FlagKeyAlphabet.Value.(string), config.FlagKeyAlphabet.Usage)
+	auditCmd.Flags().Float64P(config.FlagKeyMinEntropy.Long, config.FlagKeyMinEntropy.Short, config.FlagKeyMinEntropy.Value.(float64), config.FlagKeyMinEntropy.Usage)
+	auditCmd.Flags().IntP(config.FlagAuditMinLength.Long, config.FlagAuditMinLength.Short, config.FlagAuditMinLength.Value.(int), config.FlagAuditMinLength.Usage)
+
+	apiKeyCmd.AddCommand(auditCmd)
+}
+
+var auditCmd = &cobra.Command{
+	Use:   `audit`,
+	Short: `Reports duplicate, weak and undecryptable API keys`,
+	Long: `Reports duplicate, weak and undecryptable API keys. Every API key resource,
+read from files or, with --from-cluster, from the cluster, is decrypted. API keys
+whose data is shared with another API key resource, that are shorter than
+--min-length or whose estimated entropy is below --key.min_entropy, and that
+cannot be decrypted with the private key are reported. The command exits with a
+non-zero status if anything is reported.`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		if err := bindFlags(cmd, config.FlagRSAPrivateKeyFile, config.FlagKeyInFile, config.FlagFromCluster, config.FlagDecryptNamespace, config.FlagAllNamespaces, config.FlagDecryptConcurrency, config.FlagKeyAlphabet, config.FlagKeyMinEntropy, config.FlagAuditMinLength); err != nil {
+			logrus.Fatalf("%s", err.Error())
+			os.Exit(1)
+		}
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+
+		policy := generate.Policy{
+			Alphabet:   viper.GetString(config.FlagKeyAlphabet.GetLong()),
+			MinEntropy: viper.GetFloat64(config.FlagKeyMinEntropy.GetLong()),
+		}
+		if err := policy.Validate(); err != nil {
+			logrus.Fatalf("%s", err.Error())
+			os.Exit(1)
+		}
+
+		privateKey, err := rsakey.LoadPrivateKey(viper.GetString(config.FlagRSAPrivateKeyFile.GetLong()), rsakey.DefaultPassphrase)
+		if err != nil {
+			logrus.Fatalf("%s", err.Error())
+			os.Exit(1)
+		}
+
+		filter := decrypt.Filter{
+			Namespace: viper.GetString(config.FlagDecryptNamespace.GetLong()),
+		}
+
+		results, err := decryptKeys(privateKey, filter, viper.GetInt(config.FlagDecryptConcurrency.GetLong()))
+		if err != nil {
+			logrus.Fatalf("%s", err.Error())
+			os.Exit(1)
+		}
+
+		report := audit.Audit(results, policy, viper.GetInt(config.FlagAuditMinLength.GetLong()))
+		audit.Render(os.Stdout, report)
+
+		if len(report.Findings) > 0 {
+			os.Exit(1)
+		}
+
+		os.Exit(0)
+	},
+}
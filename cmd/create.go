@@ -24,12 +24,18 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/northwesternmutual/kanalictl/config"
 	"github.com/northwesternmutual/kanalictl/controller"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 func init() {
-	createCmd.Flags().StringP("file", "f", "", "location to configuration file")
+	createCmd.Flags().StringArrayP("file", "f", []string{}, "location to configuration file, directory or glob pattern, or - for stdin. May be repeated.")
+	createCmd.Flags().BoolP(config.FlagRecursive.Long, config.FlagRecursive.Short, config.FlagRecursive.Value.(bool), config.FlagRecursive.Usage)
+	createCmd.Flags().BoolP(config.FlagUseKubectl.Long, config.FlagUseKubectl.Short, config.FlagUseKubectl.Value.(bool), config.FlagUseKubectl.Usage)
+	createCmd.Flags().String(config.FlagDryRun.Long, config.FlagDryRun.Value.(string), config.FlagDryRun.Usage)
+	createCmd.Flags().Lookup(config.FlagDryRun.Long).NoOptDefVal = controller.DryRunClient
 	RootCmd.AddCommand(createCmd)
 }
 
@@ -37,12 +43,26 @@ var createCmd = &cobra.Command{
 	Use:   `create`,
 	Short: `Create a resource by filename.`,
 	Long:  `Create a resource by filename`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		if err := bindFlags(cmd, config.FlagRecursive, config.FlagUseKubectl); err != nil {
+			fmt.Print(err.Error())
+			os.Exit(1)
+		}
+		if err := bindFlag(cmd, config.FlagDryRun, config.KeyDryRun); err != nil {
+			fmt.Print(err.Error())
+			os.Exit(1)
+		}
+	},
 	Run: func(cmd *cobra.Command, args []string) {
-		path, err := cmd.Flags().GetString("file")
+		inputs, err := cmd.Flags().GetStringArray("file")
 		if err != nil {
 			fmt.Print(err.Error())
 			os.Exit(1)
 		}
-		os.Exit(controller.CreateOrApply("create", path))
+		os.Exit(controller.CreateOrApply("create", inputs, controller.Options{
+			UseKubectl: viper.GetBool(config.FlagUseKubectl.GetLong()),
+			Recursive:  viper.GetBool(config.FlagRecursive.GetLong()),
+			DryRun:     viper.GetString(config.KeyDryRun),
+		}))
 	},
 }
@@ -22,14 +22,15 @@ package cmd
 
 import (
 	"crypto/rsa"
-	"crypto/x509"
-	"encoding/pem"
-	"io/ioutil"
+	"errors"
 	"os"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/northwesternmutual/kanali/controller"
 	"github.com/northwesternmutual/kanalictl/config"
+	"github.com/northwesternmutual/kanalictl/pkg/cluster"
 	"github.com/northwesternmutual/kanalictl/pkg/decrypt"
+	"github.com/northwesternmutual/kanalictl/pkg/rsakey"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -37,6 +38,12 @@ import (
 func init() {
 	decryptCmd.Flags().StringP(config.FlagRSAPrivateKeyFile.Long, config.FlagRSAPrivateKeyFile.Short, config.FlagRSAPrivateKeyFile.Value.(string), config.FlagRSAPrivateKeyFile.Usage)
 	decryptCmd.Flags().StringP(config.FlagKeyInFile.Long, config.FlagKeyInFile.Short, config.FlagKeyInFile.Value.(string), config.FlagKeyInFile.Usage)
+	decryptCmd.Flags().StringP(config.FlagDecryptOutput.Long, config.FlagDecryptOutput.Short, config.FlagDecryptOutput.Value.(string), config.FlagDecryptOutput.Usage)
+	decryptCmd.Flags().StringArrayP(config.FlagDecryptNames.Long, config.FlagDecryptNames.Short, config.FlagDecryptNames.Value.([]string), config.FlagDecryptNames.Usage)
+	decryptCmd.Flags().StringP(config.FlagDecryptNamespace.Long, config.FlagDecryptNamespace.Short, config.FlagDecryptNamespace.Value.(string), config.FlagDecryptNamespace.Usage)
+	decryptCmd.Flags().BoolP(config.FlagFromCluster.Long, config.FlagFromCluster.Short, config.FlagFromCluster.Value.(bool), config.FlagFromCluster.Usage)
+	decryptCmd.Flags().BoolP(config.FlagAllNamespaces.Long, config.FlagAllNamespaces.Short, config.FlagAllNamespaces.Value.(bool), config.FlagAllNamespaces.Usage)
+	decryptCmd.Flags().IntP(config.FlagDecryptConcurrency.Long, config.FlagDecryptConcurrency.Short, config.FlagDecryptConcurrency.Value.(int), config.FlagDecryptConcurrency.Usage)
 
 	if err := viper.BindPFlag(config.FlagRSAPrivateKeyFile.Long, decryptCmd.Flags().Lookup(config.FlagRSAPrivateKeyFile.Long)); err != nil {
 		panic(err)
@@ -47,41 +54,93 @@ func init() {
 
 	viper.SetDefault(config.FlagRSAPrivateKeyFile.Long, config.FlagRSAPublicKeyFile.Value)
 	viper.SetDefault(config.FlagKeyInFile.Long, config.FlagKeyInFile.Value)
+	viper.SetDefault(config.FlagDecryptOutput.Long, config.FlagDecryptOutput.Value)
 
 	apiKeyCmd.AddCommand(decryptCmd)
 }
 
 var decryptCmd = &cobra.Command{
-	Use:   `decrypt`,
+	Use:   `decrypt [name...]`,
 	Short: `Decrypts API key resources`,
-	Long:  `Decrypts API key resources`,
+	Long: `Decrypts API key resources. API keys are read from files or, with
+--from-cluster, from the cluster. When reading from the cluster, API keys are
+read from the namespace given by --namespace, the default namespace if none is
+given, or every namespace with --all-namespaces. Names may be given as arguments
+to only decrypt those API keys.`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		if err := bindFlags(cmd, config.FlagDecryptOutput, config.FlagDecryptNamespace, config.FlagFromCluster, config.FlagAllNamespaces, config.FlagDecryptConcurrency); err != nil {
+			logrus.Fatalf("%s", err.Error())
+			os.Exit(1)
+		}
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 
-		privateKey, err := getPrivateKey(viper.GetString(config.FlagRSAPrivateKeyFile.GetLong()))
+		format, err := decrypt.ParseFormat(viper.GetString(config.FlagDecryptOutput.GetLong()))
+		if err != nil {
+			logrus.Fatalf("%s", err.Error())
+			os.Exit(1)
+		}
+
+		privateKey, err := rsakey.LoadPrivateKey(viper.GetString(config.FlagRSAPrivateKeyFile.GetLong()), rsakey.DefaultPassphrase)
+		if err != nil {
+			logrus.Fatalf("%s", err.Error())
+			os.Exit(1)
+		}
+
+		filter := decrypt.Filter{
+			Names:     append(getStringArray(cmd, config.FlagDecryptNames, config.FlagDecryptNames.GetLong()), args...),
+			Namespace: viper.GetString(config.FlagDecryptNamespace.GetLong()),
+		}
+
+		results, err := decryptKeys(privateKey, filter, viper.GetInt(config.FlagDecryptConcurrency.GetLong()))
 		if err != nil {
 			logrus.Fatalf("%s", err.Error())
 			os.Exit(1)
 		}
 
-		if err := decrypt.Do(viper.GetString(config.FlagKeyInFile.GetLong()), privateKey); err != nil {
+		if err := decrypt.Render(os.Stdout, format, results); err != nil {
 			logrus.Fatalf("%s", err.Error())
 			os.Exit(1)
 		}
 
+		if failed := decrypt.Failed(results); failed > 0 {
+			logrus.Fatalf("%d of %d documents could not be decrypted", failed, len(results))
+			os.Exit(1)
+		}
+
 		os.Exit(0)
 
 	},
 }
 
-func getPrivateKey(location string) (*rsa.PrivateKey, error) {
-	keyBytes, err := ioutil.ReadFile(location)
+// decryptKeys decrypts API keys either from the cluster or from files
+// depending on the flags provided.
+func decryptKeys(privateKey *rsa.PrivateKey, filter decrypt.Filter, concurrency int) ([]decrypt.Result, error) {
+	if !viper.GetBool(config.FlagFromCluster.GetLong()) {
+		path := viper.GetString(config.FlagKeyInFile.GetLong())
+		if len(path) < 1 {
+			return nil, errors.New("file or directory containing API key resources must be specified unless --from-cluster is used")
+		}
+		return decrypt.Files(path, privateKey, filter, concurrency)
+	}
+
+	namespace := filter.Namespace
+	if viper.GetBool(config.FlagAllNamespaces.GetLong()) {
+		namespace = ""
+	} else if len(namespace) < 1 {
+		namespace = cluster.DefaultNamespace
+	}
+	filter.Namespace = ""
+
+	ctlr, err := controller.New()
 	if err != nil {
 		return nil, err
 	}
-	block, _ := pem.Decode(keyBytes)
-	privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+
+	keys, err := cluster.APIKeys(ctlr.RestClient.Client, ctlr.MasterHost, namespace)
 	if err != nil {
 		return nil, err
 	}
-	return privateKey, nil
+
+	return decrypt.Keys(keys, "cluster", privateKey, filter, concurrency)
 }
@@ -0,0 +1,90 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/northwesternmutual/kanalictl/config"
+	"github.com/northwesternmutual/kanalictl/controller"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	deleteCmd.Flags().StringArrayP("file", "f", []string{}, "location to configuration file, directory or glob pattern, or - for stdin. May be repeated.")
+	deleteCmd.Flags().BoolP(config.FlagRecursive.Long, config.FlagRecursive.Short, config.FlagRecursive.Value.(bool), config.FlagRecursive.Usage)
+	deleteCmd.Flags().StringP(config.FlagDeleteNamespace.Long, config.FlagDeleteNamespace.Short, config.FlagDeleteNamespace.Value.(string), config.FlagDeleteNamespace.Usage)
+	deleteCmd.Flags().BoolP(config.FlagCascade.Long, config.FlagCascade.Short, config.FlagCascade.Value.(bool), config.FlagCascade.Usage)
+	deleteCmd.Flags().BoolP(config.FlagForce.Long, config.FlagForce.Short, config.FlagForce.Value.(bool), config.FlagForce.Usage)
+	RootCmd.AddCommand(deleteCmd)
+}
+
+var deleteCmd = &cobra.Command{
+	Use:   `delete ([-f FILENAME] | TYPE NAME...)`,
+	Short: `Delete resources by filename or by type and name.`,
+	Long: `Delete resources by filename or by type and name.
+
+An ApiProxy is not deleted while an ApiKeyBinding is bound to it and an
+ApiKey is not deleted while an ApiKeyBinding references it, unless --cascade
+or --force is given. With --cascade the ApiKeyBinding of a deleted ApiProxy
+is deleted too and deleted API keys are removed from the ApiKeyBindings that
+reference them. An ApiKeyBinding left without API keys is deleted.
+
+Every resource is checked before any is deleted, and nothing is deleted if a
+check fails. ApiKeyBindings are then changed or deleted first, then ApiProxies
+and then ApiKeys, one at a time. Deletion stops at the first failure, and the
+resources changed or deleted before it stay that way.`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		if err := bindFlags(cmd, config.FlagRecursive, config.FlagDeleteNamespace, config.FlagCascade, config.FlagForce); err != nil {
+			fmt.Print(err.Error())
+			os.Exit(1)
+		}
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		inputs, err := cmd.Flags().GetStringArray("file")
+		if err != nil {
+			fmt.Print(err.Error())
+			os.Exit(1)
+		}
+
+		opts := controller.DeleteOptions{
+			Cascade:   viper.GetBool(config.FlagCascade.GetLong()),
+			Force:     viper.GetBool(config.FlagForce.GetLong()),
+			Recursive: viper.GetBool(config.FlagRecursive.GetLong()),
+		}
+
+		if len(inputs) > 0 {
+			if len(args) > 0 {
+				fmt.Println("resources cannot be named when -f is given")
+				os.Exit(1)
+			}
+			os.Exit(controller.DeleteFiles(inputs, opts))
+		}
+
+		if len(args) < 1 {
+			fmt.Println("file or the type and name of the resources to delete must be specified")
+			os.Exit(1)
+		}
+		os.Exit(controller.DeleteNamed(args[0], args[1:], viper.GetString(config.FlagDeleteNamespace.GetLong()), opts))
+	},
+}
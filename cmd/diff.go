@@ -0,0 +1,65 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/northwesternmutual/kanalictl/config"
+	"github.com/northwesternmutual/kanalictl/controller"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	diffCmd.Flags().StringArrayP("file", "f", []string{}, "location to configuration file, directory or glob pattern, or - for stdin. May be repeated.")
+	diffCmd.Flags().BoolP(config.FlagRecursive.Long, config.FlagRecursive.Short, config.FlagRecursive.Value.(bool), config.FlagRecursive.Usage)
+	RootCmd.AddCommand(diffCmd)
+}
+
+var diffCmd = &cobra.Command{
+	Use:   `diff`,
+	Short: `Diff the live state of resources against a configuration.`,
+	Long: `Diff the live state of resources against a configuration.
+
+The live state is compared with the state apply would leave it in, so fields
+that apply keeps, such as labels added by others, are not shown as removed.
+Fields populated by the server are ignored and the data of an ApiKey is only
+reported as changed or unchanged.
+
+Exit status is 0 when there are no changes, 1 when there are changes and 2
+when the diff could not be made.`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		if err := bindFlags(cmd, config.FlagRecursive); err != nil {
+			fmt.Print(err.Error())
+			os.Exit(controller.DiffError)
+		}
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		inputs, err := cmd.Flags().GetStringArray("file")
+		if err != nil {
+			fmt.Print(err.Error())
+			os.Exit(controller.DiffError)
+		}
+		os.Exit(controller.Diff(inputs, viper.GetBool(config.FlagRecursive.GetLong())))
+	},
+}
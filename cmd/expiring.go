@@ -0,0 +1,128 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/northwesternmutual/kanali/controller"
+	"github.com/northwesternmutual/kanalictl/config"
+	"github.com/northwesternmutual/kanalictl/pkg/cluster"
+	"github.com/northwesternmutual/kanalictl/pkg/expiry"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	expiringCmd.Flags().StringP(config.FlagKeyInFile.Long, "f", config.FlagKeyInFile.Value.(string), config.FlagKeyInFile.Usage)
+	expiringCmd.Flags().StringP(config.FlagExpiringWithin.Long, config.FlagExpiringWithin.Short, config.FlagExpiringWithin.Value.(string), config.FlagExpiringWithin.Usage)
+	expiringCmd.Flags().StringP(config.FlagExpiringRotationAge.Long, config.FlagExpiringRotationAge.Short, config.FlagExpiringRotationAge.Value.(string), config.FlagExpiringRotationAge.Usage)
+	expiringCmd.Flags().BoolP(config.FlagFromCluster.Long, config.FlagFromCluster.Short, config.FlagFromCluster.Value.(bool), config.FlagFromCluster.Usage)
+	expiringCmd.Flags().StringP(config.FlagClusterNamespace.Long, config.FlagClusterNamespace.Short, config.FlagClusterNamespace.Value.(string), config.FlagClusterNamespace.Usage)
+	expiringCmd.Flags().BoolP(config.FlagAllNamespaces.Long, config.FlagAllNamespaces.Short, config.FlagAllNamespaces.Value.(bool), config.FlagAllNamespaces.Usage)
+
+	viper.SetDefault(config.FlagExpiringWithin.Long, config.FlagExpiringWithin.Value)
+	viper.SetDefault(config.FlagExpiringRotationAge.Long, config.FlagExpiringRotationAge.Value)
+	viper.SetDefault(config.FlagFromCluster.Long, config.FlagFromCluster.Value)
+	viper.SetDefault(config.FlagAllNamespaces.Long, config.FlagAllNamespaces.Value)
+
+	apiKeyCmd.AddCommand(expiringCmd)
+}
+
+var expiringCmd = &cobra.Command{
+	Use:   `expiring`,
+	Short: `Lists API keys that are expiring, expired or due for rotation`,
+	Long: `Lists API keys that are expiring, expired or due for rotation. API keys are
+read from files or, with --from-cluster, from the cluster. Expiry is recorded by
+the kanali.io/expires-at annotation set by 'apikey generate --expires-in'.`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		if err := bindFlags(cmd, config.FlagKeyInFile, config.FlagExpiringWithin, config.FlagExpiringRotationAge, config.FlagFromCluster, config.FlagClusterNamespace, config.FlagAllNamespaces); err != nil {
+			logrus.Fatalf("%s", err.Error())
+			os.Exit(1)
+		}
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+
+		within, err := expiry.ParseDuration(viper.GetString(config.FlagExpiringWithin.GetLong()))
+		if err != nil {
+			logrus.Fatalf("%s", err.Error())
+			os.Exit(1)
+		}
+
+		var rotationAge time.Duration
+		if age := viper.GetString(config.FlagExpiringRotationAge.GetLong()); len(age) > 0 {
+			if rotationAge, err = expiry.ParseDuration(age); err != nil {
+				logrus.Fatalf("%s", err.Error())
+				os.Exit(1)
+			}
+		}
+
+		keys, err := getLocatedKeys()
+		if err != nil {
+			logrus.Fatalf("%s", err.Error())
+			os.Exit(1)
+		}
+
+		expiry.Render(os.Stdout, expiry.Report(keys, time.Now(), within, rotationAge))
+
+		os.Exit(0)
+	},
+}
+
+// getLocatedKeys reads API keys either from the cluster or from files
+// depending on the flags provided.
+func getLocatedKeys() ([]expiry.Located, error) {
+	if !viper.GetBool(config.FlagFromCluster.GetLong()) {
+		path := viper.GetString(config.FlagKeyInFile.GetLong())
+		if len(path) < 1 {
+			return nil, errors.New("file or directory containing API key resources must be specified unless --from-cluster is used")
+		}
+		return expiry.FromFiles(path)
+	}
+
+	ctlr, err := controller.New()
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := cluster.APIKeys(ctlr.RestClient.Client, ctlr.MasterHost, getClusterNamespace())
+	if err != nil {
+		return nil, err
+	}
+
+	located := make([]expiry.Located, 0, len(keys))
+	for _, key := range keys {
+		located = append(located, expiry.Located{Key: key, Source: "cluster"})
+	}
+	return located, nil
+}
+
+// getClusterNamespace returns the namespace resources are read from in the
+// cluster. An empty namespace means every namespace.
+func getClusterNamespace() string {
+	if viper.GetBool(config.FlagAllNamespaces.GetLong()) {
+		return ""
+	}
+	return viper.GetString(config.FlagClusterNamespace.GetLong())
+}
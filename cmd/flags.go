@@ -0,0 +1,62 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"github.com/northwesternmutual/kanali/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// bindFlags binds flags of a command to their configuration keys and
+// defaults. Since several commands share the same configuration keys, and a
+// configuration key can only be bound to a single flag and default, this
+// must be done when the command runs rather than when it is initialized.
+func bindFlags(cmd *cobra.Command, flags ...config.Flag) error {
+	for _, flag := range flags {
+		if err := bindFlag(cmd, flag, flag.Long); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bindFlag binds a flag of a command to the given configuration key and
+// default, for flags whose configuration key differs from their name.
+func bindFlag(cmd *cobra.Command, flag config.Flag, key string) error {
+	if err := viper.BindPFlag(key, cmd.Flags().Lookup(flag.Long)); err != nil {
+		return err
+	}
+	viper.SetDefault(key, flag.Value)
+	return nil
+}
+
+// getStringArray returns the values given for a repeatable flag. If the
+// flag was not given, the values of the configuration key it is bound to are
+// returned.
+func getStringArray(cmd *cobra.Command, flag config.Flag, key string) []string {
+	if f := cmd.Flags().Lookup(flag.Long); f != nil && f.Changed {
+		if values, err := cmd.Flags().GetStringArray(flag.Long); err == nil {
+			return values
+		}
+	}
+	return viper.GetStringSlice(key)
+}
@@ -22,22 +22,22 @@ package cmd
 
 import (
 	"crypto/rsa"
-	"crypto/x509"
-	"encoding/pem"
 	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/Sirupsen/logrus"
-	"github.com/northwesternmutual/kanali/spec"
 	"github.com/northwesternmutual/kanalictl/config"
+	"github.com/northwesternmutual/kanalictl/pkg/expiry"
 	"github.com/northwesternmutual/kanalictl/pkg/generate"
+	"github.com/northwesternmutual/kanalictl/pkg/handoff"
+	"github.com/northwesternmutual/kanalictl/pkg/rsakey"
+	"github.com/northwesternmutual/kanalictl/utils"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-	"k8s.io/kubernetes/pkg/api"
-	"k8s.io/kubernetes/pkg/api/unversioned"
 )
 
 func init() {
@@ -46,6 +46,20 @@ func init() {
 	generateCmd.Flags().StringP(config.FlagKeyOutFile.Long, config.FlagKeyOutFile.Short, config.FlagKeyOutFile.Value.(string), config.FlagKeyOutFile.Usage)
 	generateCmd.Flags().IntP(config.FlagKeyLength.Long, config.FlagKeyLength.Short, config.FlagKeyLength.Value.(int), config.FlagKeyLength.Usage)
 	generateCmd.Flags().StringP(config.FlagKeyData.Long, config.FlagKeyData.Short, config.FlagKeyData.Value.(string), config.FlagKeyData.Usage)
+	generateCmd.Flags().StringP(config.FlagKeyAlphabet.Long, config.FlagKeyAlphabet.Short, config.FlagKeyAlphabet.Value.(string), config.FlagKeyAlphabet.Usage)
+	generateCmd.Flags().Float64P(config.FlagKeyMinEntropy.Long, config.FlagKeyMinEntropy.Short, config.FlagKeyMinEntropy.Value.(float64), config.FlagKeyMinEntropy.Usage)
+	generateCmd.Flags().StringP(config.FlagKeyRosterFile.Long, config.FlagKeyRosterFile.Short, config.FlagKeyRosterFile.Value.(string), config.FlagKeyRosterFile.Usage)
+	generateCmd.Flags().StringP(config.FlagKeyDeliveryFile.Long, config.FlagKeyDeliveryFile.Short, config.FlagKeyDeliveryFile.Value.(string), config.FlagKeyDeliveryFile.Usage)
+	generateCmd.Flags().StringP(config.FlagKeyNamespace.Long, config.FlagKeyNamespace.Short, config.FlagKeyNamespace.Value.(string), config.FlagKeyNamespace.Usage)
+	generateCmd.Flags().StringArrayP(config.FlagKeyLabels.Long, config.FlagKeyLabels.Short, config.FlagKeyLabels.Value.([]string), config.FlagKeyLabels.Usage)
+	generateCmd.Flags().StringP(config.FlagKeyOverwrite.Long, config.FlagKeyOverwrite.Short, config.FlagKeyOverwrite.Value.(string), config.FlagKeyOverwrite.Usage)
+	generateCmd.Flags().StringArrayP(config.FlagKeyAnnotations.Long, config.FlagKeyAnnotations.Short, config.FlagKeyAnnotations.Value.([]string), config.FlagKeyAnnotations.Usage)
+	generateCmd.Flags().StringArrayP(config.FlagKeyAgeRecipients.Long, config.FlagKeyAgeRecipients.Short, config.FlagKeyAgeRecipients.Value.([]string), config.FlagKeyAgeRecipients.Usage)
+	generateCmd.Flags().StringArrayP(config.FlagKeyPGPRecipients.Long, config.FlagKeyPGPRecipients.Short, config.FlagKeyPGPRecipients.Value.([]string), config.FlagKeyPGPRecipients.Usage)
+	generateCmd.Flags().StringP(config.FlagKeyExpiresIn.Long, config.FlagKeyExpiresIn.Short, config.FlagKeyExpiresIn.Value.(string), config.FlagKeyExpiresIn.Usage)
+	generateCmd.Flags().StringP(config.FlagKeyOwner.Long, config.FlagKeyOwner.Short, config.FlagKeyOwner.Value.(string), config.FlagKeyOwner.Usage)
+	generateCmd.Flags().StringP(config.FlagKeyContact.Long, config.FlagKeyContact.Short, config.FlagKeyContact.Value.(string), config.FlagKeyContact.Usage)
+	generateCmd.Flags().BoolP(config.FlagKeyAllowPlaintext.Long, config.FlagKeyAllowPlaintext.Short, config.FlagKeyAllowPlaintext.Value.(bool), config.FlagKeyAllowPlaintext.Usage)
 
 	if err := viper.BindPFlag(config.FlagKeyData.Long, generateCmd.Flags().Lookup(config.FlagKeyData.Long)); err != nil {
 		panic(err)
@@ -62,12 +76,62 @@ func init() {
 	if err := viper.BindPFlag(config.FlagKeyLength.Long, generateCmd.Flags().Lookup(config.FlagKeyLength.Long)); err != nil {
 		panic(err)
 	}
+	if err := viper.BindPFlag(config.FlagKeyAlphabet.Long, generateCmd.Flags().Lookup(config.FlagKeyAlphabet.Long)); err != nil {
+		panic(err)
+	}
+	if err := viper.BindPFlag(config.FlagKeyMinEntropy.Long, generateCmd.Flags().Lookup(config.FlagKeyMinEntropy.Long)); err != nil {
+		panic(err)
+	}
+	if err := viper.BindPFlag(config.FlagKeyRosterFile.Long, generateCmd.Flags().Lookup(config.FlagKeyRosterFile.Long)); err != nil {
+		panic(err)
+	}
+	if err := viper.BindPFlag(config.FlagKeyDeliveryFile.Long, generateCmd.Flags().Lookup(config.FlagKeyDeliveryFile.Long)); err != nil {
+		panic(err)
+	}
+	if err := viper.BindPFlag(config.KeyNamespace, generateCmd.Flags().Lookup(config.FlagKeyNamespace.Long)); err != nil {
+		panic(err)
+	}
+	if err := viper.BindPFlag(config.KeyLabels, generateCmd.Flags().Lookup(config.FlagKeyLabels.Long)); err != nil {
+		panic(err)
+	}
+	if err := viper.BindPFlag(config.KeyAnnotations, generateCmd.Flags().Lookup(config.FlagKeyAnnotations.Long)); err != nil {
+		panic(err)
+	}
+	if err := viper.BindPFlag(config.FlagKeyOverwrite.Long, generateCmd.Flags().Lookup(config.FlagKeyOverwrite.Long)); err != nil {
+		panic(err)
+	}
+	if err := viper.BindPFlag(config.FlagKeyAllowPlaintext.Long, generateCmd.Flags().Lookup(config.FlagKeyAllowPlaintext.Long)); err != nil {
+		panic(err)
+	}
+	if err := viper.BindPFlag(config.FlagKeyExpiresIn.Long, generateCmd.Flags().Lookup(config.FlagKeyExpiresIn.Long)); err != nil {
+		panic(err)
+	}
+	if err := viper.BindPFlag(config.FlagKeyOwner.Long, generateCmd.Flags().Lookup(config.FlagKeyOwner.Long)); err != nil {
+		panic(err)
+	}
+	if err := viper.BindPFlag(config.FlagKeyContact.Long, generateCmd.Flags().Lookup(config.FlagKeyContact.Long)); err != nil {
+		panic(err)
+	}
 
 	viper.SetDefault(config.FlagKeyData.Long, config.FlagKeyData.Value)
 	viper.SetDefault(config.FlagRSAPublicKeyFile.Long, config.FlagRSAPublicKeyFile.Value)
 	viper.SetDefault(config.FlagKeyName.Long, config.FlagKeyName.Value)
 	viper.SetDefault(config.FlagKeyOutFile.Long, config.FlagKeyOutFile.Value)
 	viper.SetDefault(config.FlagKeyLength.Long, config.FlagKeyLength.Value)
+	viper.SetDefault(config.FlagKeyAlphabet.Long, config.FlagKeyAlphabet.Value)
+	viper.SetDefault(config.FlagKeyMinEntropy.Long, config.FlagKeyMinEntropy.Value)
+	viper.SetDefault(config.FlagKeyRosterFile.Long, config.FlagKeyRosterFile.Value)
+	viper.SetDefault(config.FlagKeyDeliveryFile.Long, config.FlagKeyDeliveryFile.Value)
+	viper.SetDefault(config.KeyNamespace, config.FlagKeyNamespace.Value)
+	viper.SetDefault(config.KeyLabels, config.FlagKeyLabels.Value)
+	viper.SetDefault(config.KeyAnnotations, config.FlagKeyAnnotations.Value)
+	viper.SetDefault(config.FlagKeyOverwrite.Long, config.FlagKeyOverwrite.Value)
+	viper.SetDefault(config.FlagKeyAgeRecipients.Long, config.FlagKeyAgeRecipients.Value)
+	viper.SetDefault(config.FlagKeyPGPRecipients.Long, config.FlagKeyPGPRecipients.Value)
+	viper.SetDefault(config.FlagKeyAllowPlaintext.Long, config.FlagKeyAllowPlaintext.Value)
+	viper.SetDefault(config.FlagKeyExpiresIn.Long, config.FlagKeyExpiresIn.Value)
+	viper.SetDefault(config.FlagKeyOwner.Long, config.FlagKeyOwner.Value)
+	viper.SetDefault(config.FlagKeyContact.Long, config.FlagKeyContact.Value)
 
 	apiKeyCmd.AddCommand(generateCmd)
 }
@@ -78,44 +142,107 @@ var generateCmd = &cobra.Command{
 	Long:  `Creates an API key`,
 	Run: func(cmd *cobra.Command, args []string) {
 
+		publicKey, err := rsakey.LoadPublicKey(viper.GetString(config.FlagRSAPublicKeyFile.GetLong()))
+		if err != nil {
+			logrus.Fatalf("%s", err.Error())
+			os.Exit(1)
+		}
+
+		policy := generate.Policy{
+			Alphabet:   viper.GetString(config.FlagKeyAlphabet.GetLong()),
+			MinEntropy: viper.GetFloat64(config.FlagKeyMinEntropy.GetLong()),
+		}
+
+		meta, err := getMetadata(cmd)
+		if err != nil {
+			logrus.Fatalf("%s", err.Error())
+			os.Exit(1)
+		}
+
+		deliveryFile := viper.GetString(config.FlagKeyDeliveryFile.GetLong())
+
+		recipients, err := getRecipients(cmd, deliveryFile)
+		if err != nil {
+			logrus.Fatalf("%s", err.Error())
+			os.Exit(1)
+		}
+
+		if rosterFile := viper.GetString(config.FlagKeyRosterFile.GetLong()); len(rosterFile) > 0 {
+			if err := generateFromRoster(rosterFile, meta, policy, publicKey, recipients); err != nil {
+				logrus.Fatalf("%s", err.Error())
+				os.Exit(1)
+			}
+			os.Exit(0)
+		}
+
+		if err := meta.Validate(); err != nil {
+			logrus.Fatalf("%s", err.Error())
+			os.Exit(1)
+		}
+
 		outFileName, err := getOutFile(viper.GetString(config.FlagKeyOutFile.GetLong()))
 		if err != nil {
 			logrus.Fatalf("%s", err.Error())
 			os.Exit(1)
 		}
 
-		publicKey, err := getPublicKey(viper.GetString(config.FlagRSAPublicKeyFile.GetLong()))
+		overwrite, err := generate.ParseOverwrite(viper.GetString(config.FlagKeyOverwrite.GetLong()))
+		if err != nil {
+			logrus.Fatalf("%s", err.Error())
+			os.Exit(1)
+		}
+
+		keyName := viper.GetString(config.FlagKeyName.GetLong())
+
+		overwrite, err = generate.CheckOutFile(outFileName, overwrite, keyName, meta.Namespace)
+		if err != nil {
+			logrus.Fatalf("%s", err.Error())
+			os.Exit(1)
+		}
+
+		// when the config is written to stdout, keep it free of everything else
+		display := os.Stdout
+		if outFileName == generate.Stdout {
+			display = os.Stderr
+		}
+
+		// decide where the unencrypted api key goes before it exists
+		if len(deliveryFile) > 0 {
+			err = generate.CheckDeliveryFile(deliveryFile)
+		} else {
+			err = checkPlaintext(display)
+		}
 		if err != nil {
 			logrus.Fatalf("%s", err.Error())
 			os.Exit(1)
 		}
 
-		unencryptedKeyData, encryptedKeyData, err := generate.Key(viper.GetString(config.FlagKeyName.GetLong()), viper.GetString(config.FlagKeyData.GetLong()), viper.GetInt(config.FlagKeyLength.GetLong()), publicKey)
+		unencryptedKeyData, encryptedKeyData, entropy, err := generate.Key(keyName, viper.GetString(config.FlagKeyData.GetLong()), viper.GetInt(config.FlagKeyLength.GetLong()), policy, publicKey)
 		if err != nil {
 			logrus.Fatalf("%s", err.Error())
 			os.Exit(1)
 		}
 
-		keyCRD := spec.APIKey{
-			TypeMeta: unversioned.TypeMeta{
-				APIVersion: "kanali.io/v1",
-				Kind:       "ApiKey",
-			},
-			ObjectMeta: api.ObjectMeta{
-				Name:      viper.GetString(config.FlagKeyName.GetLong()),
-				Namespace: "default",
-			},
-			Spec: spec.APIKeySpec{
-				APIKeyData: fmt.Sprintf("%x", encryptedKeyData),
-			},
+		keyCRD := generate.CRD(keyName, meta, encryptedKeyData)
+
+		if len(deliveryFile) > 0 {
+			if err := writeDelivery(display, deliveryFile, []generate.GeneratedKey{{
+				CRD:                keyCRD,
+				UnencryptedKeyData: unencryptedKeyData,
+				Entropy:            entropy,
+			}}, recipients); err != nil {
+				logrus.Fatalf("%s", err.Error())
+				os.Exit(1)
+			}
+			unencryptedKeyData = nil
 		}
 
-		if err := generate.Display(len(outFileName) < 1, unencryptedKeyData, keyCRD); err != nil {
+		if err := generate.Display(display, len(outFileName) < 1, unencryptedKeyData, entropy, keyCRD); err != nil {
 			logrus.Fatalf("%s", err.Error())
 			os.Exit(1)
 		}
 
-		if err := generate.Write(outFileName, keyCRD); err != nil {
+		if err := generate.Write(outFileName, overwrite, keyCRD); err != nil {
 			logrus.Fatalf("%s", err.Error())
 			os.Exit(1)
 		}
@@ -124,54 +251,131 @@ var generateCmd = &cobra.Command{
 	},
 }
 
-func getOutFile(f string) (string, error) {
-	if len(f) < 1 {
-		return "", nil
+func getMetadata(cmd *cobra.Command) (generate.Metadata, error) {
+	labels, err := utils.ParseKeyValues(getStringArray(cmd, config.FlagKeyLabels, config.KeyLabels))
+	if err != nil {
+		return generate.Metadata{}, fmt.Errorf("invalid label: %s", err.Error())
 	}
 
-	ext := filepath.Ext(f)
-	if len(ext) < 1 {
-		return f + ".yaml", nil
+	annotations, err := utils.ParseKeyValues(getStringArray(cmd, config.FlagKeyAnnotations, config.KeyAnnotations))
+	if err != nil {
+		return generate.Metadata{}, fmt.Errorf("invalid annotation: %s", err.Error())
 	}
 
-	switch ext {
-	case ".yaml", ".yml", ".json":
-	default:
-		return "", errors.New("out file just be either json or yaml format")
+	lifecycle := expiry.Lifecycle{
+		Owner:   viper.GetString(config.FlagKeyOwner.GetLong()),
+		Contact: viper.GetString(config.FlagKeyContact.GetLong()),
+	}
+	if expiresIn := viper.GetString(config.FlagKeyExpiresIn.GetLong()); len(expiresIn) > 0 {
+		if lifecycle.ExpiresIn, err = expiry.ParseDuration(expiresIn); err != nil {
+			return generate.Metadata{}, err
+		}
+		if lifecycle.ExpiresIn <= 0 {
+			return generate.Metadata{}, errors.New("expires in must be greater than zero")
+		}
 	}
 
-	return f, nil
+	// explicitly provided annotations take precedence
+	return generate.Metadata{
+		Namespace:   viper.GetString(config.KeyNamespace),
+		Labels:      labels,
+		Annotations: lifecycle.Annotations(time.Now()),
+	}.Merge(generate.Metadata{
+		Annotations: annotations,
+	}), nil
 }
 
-func getPublicKey(location string) (pubKey *rsa.PublicKey, returnError error) {
+func generateFromRoster(rosterFile string, meta generate.Metadata, policy generate.Policy, publicKey *rsa.PublicKey, recipients *handoff.Recipients) error {
+	entries, err := generate.ReadRoster(rosterFile)
+	if err != nil {
+		return err
+	}
 
-	defer func() {
-		if r := recover(); r != nil {
-			returnError = errors.New("error parsing public key")
-		}
-	}()
+	length := viper.GetInt(config.FlagKeyLength.GetLong())
+	outFile := viper.GetString(config.FlagKeyOutFile.GetLong())
+	deliveryFile := viper.GetString(config.FlagKeyDeliveryFile.GetLong())
 
-	var keyBytes []byte
+	if err := generate.ValidateRoster(entries, meta, length, policy); err != nil {
+		return err
+	}
 
-	// read in public key
-	keyBytes, err := ioutil.ReadFile(location)
+	overwrite, err := generate.ParseOverwrite(viper.GetString(config.FlagKeyOverwrite.GetLong()))
 	if err != nil {
-		if location == "" {
-			return nil, errors.New("public key not specified")
-		}
-		keyBytes = []byte(location)
+		return err
+	}
+
+	if err := generate.CheckRosterOutput(outFile, deliveryFile, entries, meta, overwrite); err != nil {
+		return err
+	}
+
+	keys, err := generate.KeysFromRoster(entries, meta, length, policy, publicKey)
+	if err != nil {
+		return err
+	}
+
+	if err := writeDelivery(os.Stdout, deliveryFile, keys, recipients); err != nil {
+		return err
+	}
+
+	if err := generate.WriteAll(outFile, keys); err != nil {
+		return err
+	}
+
+	fmt.Printf("Generated %d api keys\n", len(keys))
+	return nil
+}
+
+// getRecipients loads the recipients the delivery file is encrypted for.
+func getRecipients(cmd *cobra.Command, deliveryFile string) (*handoff.Recipients, error) {
+	recipients, err := handoff.LoadRecipients(getStringArray(cmd, config.FlagKeyAgeRecipients, config.FlagKeyAgeRecipients.GetLong()), getStringArray(cmd, config.FlagKeyPGPRecipients, config.FlagKeyPGPRecipients.GetLong()))
+	if err != nil {
+		return nil, err
+	}
+
+	if recipients.Len() > 0 && len(deliveryFile) < 1 {
+		return nil, errors.New("delivery file must be specified when encrypting api keys for recipients")
+	}
+
+	return recipients, nil
+}
+
+// checkPlaintext refuses to display unencrypted api keys anywhere other than
+// a terminal, where they would end up in logs, unless explicitly allowed.
+func checkPlaintext(display *os.File) error {
+	if viper.GetBool(config.FlagKeyAllowPlaintext.GetLong()) || utils.IsTerminal(display) {
+		return nil
+	}
+	return fmt.Errorf("refusing to display an unencrypted api key on something other than a terminal - use --%s or --%s", config.FlagKeyDeliveryFile.GetLong(), config.FlagKeyAllowPlaintext.GetLong())
+}
+
+func writeDelivery(w io.Writer, deliveryFile string, keys []generate.GeneratedKey, recipients *handoff.Recipients) error {
+	if err := generate.WriteDelivery(deliveryFile, keys, recipients); err != nil {
+		return err
 	}
 
-	// create a pem block from the public key provided
-	block, _ := pem.Decode(keyBytes)
-	// parse the pem block into a public key
-	publicKeyInterface, err := x509.ParsePKIXPublicKey(block.Bytes)
-	// type assertion
-	publicKey, ok := publicKeyInterface.(*rsa.PublicKey)
-	if !ok {
-		return nil, errors.New("error parsing public key")
+	if recipients.Len() > 0 {
+		fmt.Fprintf(w, "Encrypted api keys written to %s\n", deliveryFile)
+	} else {
+		fmt.Fprintf(w, "Unencrypted api keys written to %s\n", deliveryFile)
+	}
+	return nil
+}
+
+func getOutFile(f string) (string, error) {
+	if len(f) < 1 || f == generate.Stdout {
+		return f, nil
 	}
 
-	return publicKey, nil
+	ext := filepath.Ext(f)
+	if len(ext) < 1 {
+		return f + ".yaml", nil
+	}
 
+	switch ext {
+	case ".yaml", ".yml", ".json":
+	default:
+		return "", errors.New("out file just be either json or yaml format")
+	}
+
+	return f, nil
 }
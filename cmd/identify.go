@@ -0,0 +1,166 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/northwesternmutual/kanali/controller"
+	"github.com/northwesternmutual/kanalictl/config"
+	"github.com/northwesternmutual/kanalictl/pkg/cluster"
+	"github.com/northwesternmutual/kanalictl/pkg/decrypt"
+	"github.com/northwesternmutual/kanalictl/pkg/identify"
+	"github.com/northwesternmutual/kanalictl/pkg/rsakey"
+	"github.com/northwesternmutual/kanalictl/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+func init() {
+	identifyCmd.Flags().StringP(config.FlagRSAPrivateKeyFile.Long, config.FlagRSAPrivateKeyFile.Short, config.FlagRSAPrivateKeyFile.Value.(string), config.FlagRSAPrivateKeyFile.Usage)
+	identifyCmd.Flags().StringP(config.FlagKeyInFile.Long, "f", config.FlagKeyInFile.Value.(string), config.FlagKeyInFile.Usage)
+	identifyCmd.Flags().BoolP(config.FlagFromCluster.Long, config.FlagFromCluster.Short, config.FlagFromCluster.Value.(bool), config.FlagFromCluster.Usage)
+	identifyCmd.Flags().StringP(config.FlagDecryptNamespace.Long, config.FlagDecryptNamespace.Short, config.FlagDecryptNamespace.Value.(string), config.FlagDecryptNamespace.Usage)
+	identifyCmd.Flags().BoolP(config.FlagAllNamespaces.Long, config.FlagAllNamespaces.Short, config.FlagAllNamespaces.Value.(bool), config.FlagAllNamespaces.Usage)
+	identifyCmd.Flags().IntP(config.FlagDecryptConcurrency.Long, config.FlagDecryptConcurrency.Short, config.FlagDecryptConcurrency.Value.(int), config.FlagDecryptConcurrency.Usage)
+
+	apiKeyCmd.AddCommand(identifyCmd)
+}
+
+var identifyCmd = &cobra.Command{
+	Use:   `identify`,
+	Short: `Finds the API key resource of a presented API key`,
+	Long: `Finds the API key resource of a presented API key. The API key is read from
+stdin or, if stdin is a terminal, prompted for without being echoed. It is
+compared against every API key resource, read from files or, with --from-cluster,
+from the cluster. Only the names of matching API key resources and the
+ApiKeyBindings and ApiProxies they can reach are printed.`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		if err := bindFlags(cmd, config.FlagRSAPrivateKeyFile, config.FlagKeyInFile, config.FlagFromCluster, config.FlagDecryptNamespace, config.FlagAllNamespaces, config.FlagDecryptConcurrency); err != nil {
+			logrus.Fatalf("%s", err.Error())
+			os.Exit(1)
+		}
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+
+		privateKey, err := rsakey.LoadPrivateKey(viper.GetString(config.FlagRSAPrivateKeyFile.GetLong()), rsakey.DefaultPassphrase)
+		if err != nil {
+			logrus.Fatalf("%s", err.Error())
+			os.Exit(1)
+		}
+
+		presented, err := readPresentedKey()
+		if err != nil {
+			logrus.Fatalf("%s", err.Error())
+			os.Exit(1)
+		}
+
+		filter := decrypt.Filter{
+			Namespace: viper.GetString(config.FlagDecryptNamespace.GetLong()),
+		}
+
+		results, err := decryptKeys(privateKey, filter, viper.GetInt(config.FlagDecryptConcurrency.GetLong()))
+		if err != nil {
+			logrus.Fatalf("%s", err.Error())
+			os.Exit(1)
+		}
+
+		resources, err := getResources()
+		if err != nil {
+			logrus.Fatalf("%s", err.Error())
+			os.Exit(1)
+		}
+
+		if failed := decrypt.Failed(results); failed > 0 {
+			fmt.Fprintf(os.Stderr, "%d documents could not be decrypted and were not compared\n", failed)
+		}
+
+		matches := identify.Identify(results, presented, resources)
+		identify.Render(os.Stdout, matches)
+
+		if len(matches) < 1 {
+			os.Exit(1)
+		}
+
+		os.Exit(0)
+	},
+}
+
+// readPresentedKey reads the API key to identify from stdin or, if stdin is
+// a terminal, prompts for it without echoing it.
+func readPresentedKey() ([]byte, error) {
+	var key []byte
+
+	if utils.IsTerminal(os.Stdin) {
+		fmt.Fprint(os.Stderr, "Enter API key: ")
+		data, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return nil, err
+		}
+		key = data
+	} else {
+		data, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, err
+		}
+		key = bytes.TrimRight(data, "\r\n")
+	}
+
+	if len(key) < 1 {
+		return nil, errors.New("no api key was presented")
+	}
+
+	return key, nil
+}
+
+// getResources reads the ApiKeyBindings and ApiProxies an API key may reach
+// either from the cluster or from files depending on the flags provided.
+// Since an ApiKeyBinding in any namespace may reference an API key, they are
+// read from every namespace.
+func getResources() (identify.Resources, error) {
+	if !viper.GetBool(config.FlagFromCluster.GetLong()) {
+		return identify.FromFiles(viper.GetString(config.FlagKeyInFile.GetLong()))
+	}
+
+	ctlr, err := controller.New()
+	if err != nil {
+		return identify.Resources{}, err
+	}
+
+	bindings, err := cluster.APIKeyBindings(ctlr.RestClient.Client, ctlr.MasterHost, "")
+	if err != nil {
+		return identify.Resources{}, err
+	}
+
+	proxies, err := cluster.APIProxies(ctlr.RestClient.Client, ctlr.MasterHost, "")
+	if err != nil {
+		return identify.Resources{}, err
+	}
+
+	return identify.Resources{Bindings: bindings, Proxies: proxies}, nil
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/ghodss/yaml"
+	"github.com/northwesternmutual/kanalictl/config"
+	"github.com/northwesternmutual/kanalictl/pkg/generate"
+	"github.com/northwesternmutual/kanalictl/pkg/keygen"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	keygenCmd.Flags().StringP(config.FlagRSAPrivateKeyFile.Long, config.FlagRSAPrivateKeyFile.Short, config.FlagRSAPrivateKeyFile.Value.(string), config.FlagRSAPrivateKeyFile.Usage)
+	keygenCmd.Flags().StringP(config.FlagRSAPublicKeyFile.Long, config.FlagRSAPublicKeyFile.Short, config.FlagRSAPublicKeyFile.Value.(string), config.FlagRSAPublicKeyFile.Usage)
+	keygenCmd.Flags().IntP(config.FlagRSABits.Long, config.FlagRSABits.Short, config.FlagRSABits.Value.(int), config.FlagRSABits.Usage)
+	keygenCmd.Flags().StringP(config.FlagRSASecretFile.Long, config.FlagRSASecretFile.Short, config.FlagRSASecretFile.Value.(string), config.FlagRSASecretFile.Usage)
+	keygenCmd.Flags().StringP(config.FlagRSASecretName.Long, config.FlagRSASecretName.Short, config.FlagRSASecretName.Value.(string), config.FlagRSASecretName.Usage)
+	keygenCmd.Flags().StringP(config.FlagRSASecretNamespace.Long, config.FlagRSASecretNamespace.Short, config.FlagRSASecretNamespace.Value.(string), config.FlagRSASecretNamespace.Usage)
+	keygenCmd.Flags().StringP(config.FlagRSASecretKey.Long, config.FlagRSASecretKey.Short, config.FlagRSASecretKey.Value.(string), config.FlagRSASecretKey.Usage)
+
+	viper.SetDefault(config.FlagRSABits.Long, config.FlagRSABits.Value)
+	viper.SetDefault(config.FlagRSASecretFile.Long, config.FlagRSASecretFile.Value)
+	viper.SetDefault(config.FlagRSASecretName.Long, config.FlagRSASecretName.Value)
+	viper.SetDefault(config.FlagRSASecretNamespace.Long, config.FlagRSASecretNamespace.Value)
+	viper.SetDefault(config.FlagRSASecretKey.Long, config.FlagRSASecretKey.Value)
+
+	rsaCmd.AddCommand(keygenCmd)
+}
+
+var keygenCmd = &cobra.Command{
+	Use:   `keygen`,
+	Short: `Creates the RSA key pair used to encrypt and decrypt API keys`,
+	Long: `Creates the RSA key pair used to encrypt and decrypt API keys. The public
+key is used by kanalictl to encrypt API keys. The private key is written both
+to a file and to the Kubernetes Secret the Kanali gateway mounts to decrypt them.`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		if err := bindFlags(cmd, config.FlagRSAPrivateKeyFile, config.FlagRSAPublicKeyFile, config.FlagRSABits, config.FlagRSASecretFile, config.FlagRSASecretName, config.FlagRSASecretNamespace, config.FlagRSASecretKey); err != nil {
+			logrus.Fatalf("%s", err.Error())
+			os.Exit(1)
+		}
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+
+		privateKeyFile := viper.GetString(config.FlagRSAPrivateKeyFile.GetLong())
+		publicKeyFile := viper.GetString(config.FlagRSAPublicKeyFile.GetLong())
+		secretFile := viper.GetString(config.FlagRSASecretFile.GetLong())
+
+		if len(privateKeyFile) < 1 || len(publicKeyFile) < 1 {
+			logrus.Fatalf("both --%s and --%s must be specified", config.FlagRSAPrivateKeyFile.GetLong(), config.FlagRSAPublicKeyFile.GetLong())
+			os.Exit(1)
+		}
+
+		files := []string{privateKeyFile, publicKeyFile}
+		if secretFile != generate.Stdout {
+			files = append(files, secretFile)
+		}
+		if err := keygen.CheckFiles(files...); err != nil {
+			logrus.Fatalf("%s", err.Error())
+			os.Exit(1)
+		}
+
+		kp, err := keygen.Generate(viper.GetInt(config.FlagRSABits.GetLong()))
+		if err != nil {
+			logrus.Fatalf("%s", err.Error())
+			os.Exit(1)
+		}
+
+		fingerprint, err := keygen.Fingerprint(&kp.PrivateKey.PublicKey)
+		if err != nil {
+			logrus.Fatalf("%s", err.Error())
+			os.Exit(1)
+		}
+
+		secretData, err := yaml.Marshal(keygen.Secret(kp,
+			viper.GetString(config.FlagRSASecretName.GetLong()),
+			viper.GetString(config.FlagRSASecretNamespace.GetLong()),
+			viper.GetString(config.FlagRSASecretKey.GetLong()),
+		))
+		if err != nil {
+			logrus.Fatalf("%s", err.Error())
+			os.Exit(1)
+		}
+
+		if err := keygen.WriteFiles(kp, privateKeyFile, publicKeyFile); err != nil {
+			logrus.Fatalf("%s", err.Error())
+			os.Exit(1)
+		}
+
+		// when the secret is written to stdout, keep it free of everything else
+		display := os.Stdout
+		if secretFile == generate.Stdout {
+			display = os.Stderr
+			if _, err := os.Stdout.Write(secretData); err != nil {
+				logrus.Fatalf("%s", err.Error())
+				os.Exit(1)
+			}
+		} else {
+			if err := keygen.WriteSecret(secretFile, secretData); err != nil {
+				logrus.Fatalf("%s", err.Error())
+				os.Exit(1)
+			}
+			fmt.Fprintf(display, "Kubernetes Secret written to %s\n", secretFile)
+		}
+
+		fmt.Fprintf(display, "RSA private key written to %s\n", privateKeyFile)
+		fmt.Fprintf(display, "RSA public key written to %s\n", publicKeyFile)
+		fmt.Fprintf(display, "Fingerprint of public key: %s\n", fingerprint)
+
+		os.Exit(0)
+	},
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/northwesternmutual/kanalictl/config"
+	"github.com/northwesternmutual/kanalictl/pkg/rewrap"
+	"github.com/northwesternmutual/kanalictl/pkg/rsakey"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	rewrapCmd.Flags().StringP(config.FlagKeyInFile.Long, "f", config.FlagKeyInFile.Value.(string), config.FlagKeyInFile.Usage)
+	rewrapCmd.Flags().StringP(config.FlagRewrapOldPrivateKeyFile.Long, config.FlagRewrapOldPrivateKeyFile.Short, config.FlagRewrapOldPrivateKeyFile.Value.(string), config.FlagRewrapOldPrivateKeyFile.Usage)
+	rewrapCmd.Flags().StringP(config.FlagRewrapNewPublicKeyFile.Long, config.FlagRewrapNewPublicKeyFile.Short, config.FlagRewrapNewPublicKeyFile.Value.(string), config.FlagRewrapNewPublicKeyFile.Usage)
+	rewrapCmd.Flags().BoolP(config.FlagRewrapCheck.Long, config.FlagRewrapCheck.Short, config.FlagRewrapCheck.Value.(bool), config.FlagRewrapCheck.Usage)
+
+	viper.SetDefault(config.FlagRewrapOldPrivateKeyFile.Long, config.FlagRewrapOldPrivateKeyFile.Value)
+	viper.SetDefault(config.FlagRewrapNewPublicKeyFile.Long, config.FlagRewrapNewPublicKeyFile.Value)
+	viper.SetDefault(config.FlagRewrapCheck.Long, config.FlagRewrapCheck.Value)
+
+	apiKeyCmd.AddCommand(rewrapCmd)
+}
+
+var rewrapCmd = &cobra.Command{
+	Use:   `rewrap`,
+	Short: `Re-encrypts API key resources under a new RSA key pair`,
+	Long: `Re-encrypts API key resources under a new RSA key pair. Every API key
+resource found under the specified file or directory is decrypted using the
+old private key, encrypted using the new public key and rewritten in place.
+With --check, the files that would change are reported, nothing is written and
+the exit code is non zero if any file would change.`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		if err := bindFlags(cmd, config.FlagKeyInFile, config.FlagRewrapOldPrivateKeyFile, config.FlagRewrapNewPublicKeyFile, config.FlagRewrapCheck); err != nil {
+			logrus.Fatalf("%s", err.Error())
+			os.Exit(1)
+		}
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+
+		path := viper.GetString(config.FlagKeyInFile.GetLong())
+		if len(path) < 1 {
+			logrus.Fatalf("file or directory containing API key resources must be specified")
+			os.Exit(1)
+		}
+
+		oldKey, err := rsakey.LoadPrivateKey(viper.GetString(config.FlagRewrapOldPrivateKeyFile.GetLong()), rsakey.DefaultPassphrase)
+		if err != nil {
+			logrus.Fatalf("%s", err.Error())
+			os.Exit(1)
+		}
+
+		newKey, err := rsakey.LoadPublicKey(viper.GetString(config.FlagRewrapNewPublicKeyFile.GetLong()))
+		if err != nil {
+			logrus.Fatalf("%s", err.Error())
+			os.Exit(1)
+		}
+
+		check := viper.GetBool(config.FlagRewrapCheck.GetLong())
+
+		results, err := rewrap.Do(path, oldKey, newKey, check)
+		if err != nil {
+			logrus.Fatalf("%s", err.Error())
+			os.Exit(1)
+		}
+
+		verb := "re-encrypted"
+		if check {
+			verb = "would be re-encrypted"
+		}
+
+		code := 0
+		for _, result := range results {
+			for _, name := range result.Keys {
+				fmt.Printf("%s: ApiKey %s %s\n", result.File, name, verb)
+			}
+			for _, err := range result.Errors {
+				fmt.Printf("%s: %s\n", result.File, err.Error())
+			}
+			if len(result.Errors) > 0 || (check && len(result.Keys) > 0) {
+				code = 1
+			}
+		}
+
+		if len(results) < 1 {
+			fmt.Println("no API keys found")
+		}
+
+		os.Exit(code)
+	},
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/northwesternmutual/kanalictl/config"
+	"github.com/northwesternmutual/kanalictl/pkg/generate"
+	"github.com/northwesternmutual/kanalictl/pkg/rotate"
+	"github.com/northwesternmutual/kanalictl/pkg/rsakey"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	rotateCmd.Flags().StringP(config.FlagKeyInFile.Long, "f", config.FlagKeyInFile.Value.(string), config.FlagKeyInFile.Usage)
+	rotateCmd.Flags().StringP(config.FlagRSAPublicKeyFile.Long, config.FlagRSAPublicKeyFile.Short, config.FlagRSAPublicKeyFile.Value.(string), config.FlagRSAPublicKeyFile.Usage)
+	rotateCmd.Flags().IntP(config.FlagKeyLength.Long, config.FlagKeyLength.Short, config.FlagKeyLength.Value.(int), config.FlagKeyLength.Usage)
+	rotateCmd.Flags().StringP(config.FlagKeyAlphabet.Long, config.FlagKeyAlphabet.Short, config.FlagKeyAlphabet.Value.(string), config.FlagKeyAlphabet.Usage)
+	rotateCmd.Flags().Float64P(config.FlagKeyMinEntropy.Long, config.FlagKeyMinEntropy.Short, config.FlagKeyMinEntropy.Value.(float64), config.FlagKeyMinEntropy.Usage)
+	rotateCmd.Flags().StringP(config.FlagRotateNamespace.Long, config.FlagRotateNamespace.Short, config.FlagRotateNamespace.Value.(string), config.FlagRotateNamespace.Usage)
+	rotateCmd.Flags().BoolP(config.FlagRotateFinalize.Long, config.FlagRotateFinalize.Short, config.FlagRotateFinalize.Value.(bool), config.FlagRotateFinalize.Usage)
+	rotateCmd.Flags().StringP(config.FlagKeyDeliveryFile.Long, config.FlagKeyDeliveryFile.Short, config.FlagKeyDeliveryFile.Value.(string), config.FlagKeyDeliveryFile.Usage)
+	rotateCmd.Flags().StringArrayP(config.FlagKeyAgeRecipients.Long, config.FlagKeyAgeRecipients.Short, config.FlagKeyAgeRecipients.Value.([]string), config.FlagKeyAgeRecipients.Usage)
+	rotateCmd.Flags().StringArrayP(config.FlagKeyPGPRecipients.Long, config.FlagKeyPGPRecipients.Short, config.FlagKeyPGPRecipients.Value.([]string), config.FlagKeyPGPRecipients.Usage)
+	rotateCmd.Flags().BoolP(config.FlagKeyAllowPlaintext.Long, config.FlagKeyAllowPlaintext.Short, config.FlagKeyAllowPlaintext.Value.(bool), config.FlagKeyAllowPlaintext.Usage)
+
+	viper.SetDefault(config.FlagRotateFinalize.Long, config.FlagRotateFinalize.Value)
+
+	apiKeyCmd.AddCommand(rotateCmd)
+}
+
+var rotateCmd = &cobra.Command{
+	Use:   `rotate <name>`,
+	Short: `Rotates an API key`,
+	Long: `Rotates an API key in two steps. The first step creates a successor API key
+and grants it the same access as the existing API key in every ApiKeyBinding
+that references it, in any namespace. Once consumers have switched to the
+successor, running with --finalize removes the existing API key from those
+ApiKeyBindings and deletes its config.`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		if err := bindFlags(cmd, config.FlagKeyInFile, config.FlagRSAPublicKeyFile, config.FlagKeyLength, config.FlagKeyAlphabet, config.FlagKeyMinEntropy, config.FlagRotateNamespace, config.FlagRotateFinalize, config.FlagKeyDeliveryFile, config.FlagKeyAllowPlaintext); err != nil {
+			logrus.Fatalf("%s", err.Error())
+			os.Exit(1)
+		}
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+
+		if len(args) != 1 {
+			logrus.Fatalf("exactly one API key name must be specified")
+			os.Exit(1)
+		}
+
+		path := viper.GetString(config.FlagKeyInFile.GetLong())
+		if len(path) < 1 {
+			logrus.Fatalf("file or directory containing API key resources must be specified")
+			os.Exit(1)
+		}
+
+		key := rotate.Key{
+			Namespace: viper.GetString(config.FlagRotateNamespace.GetLong()),
+			Name:      args[0],
+		}
+
+		if viper.GetBool(config.FlagRotateFinalize.GetLong()) {
+			changes, err := rotate.Finalize(path, key)
+			if err != nil {
+				logrus.Fatalf("%s", err.Error())
+				os.Exit(1)
+			}
+			displayChanges(changes)
+			os.Exit(0)
+		}
+
+		publicKey, err := rsakey.LoadPublicKey(viper.GetString(config.FlagRSAPublicKeyFile.GetLong()))
+		if err != nil {
+			logrus.Fatalf("%s", err.Error())
+			os.Exit(1)
+		}
+
+		policy := generate.Policy{
+			Alphabet:   viper.GetString(config.FlagKeyAlphabet.GetLong()),
+			MinEntropy: viper.GetFloat64(config.FlagKeyMinEntropy.GetLong()),
+		}
+
+		deliveryFile := viper.GetString(config.FlagKeyDeliveryFile.GetLong())
+
+		recipients, err := getRecipients(cmd, deliveryFile)
+		if err != nil {
+			logrus.Fatalf("%s", err.Error())
+			os.Exit(1)
+		}
+
+		// decide where the unencrypted api key goes before it exists
+		if len(deliveryFile) > 0 {
+			err = generate.CheckDeliveryFile(deliveryFile)
+		} else {
+			err = checkPlaintext(os.Stdout)
+		}
+		if err != nil {
+			logrus.Fatalf("%s", err.Error())
+			os.Exit(1)
+		}
+
+		result, err := rotate.Start(path, key, viper.GetInt(config.FlagKeyLength.GetLong()), policy, publicKey)
+		if err != nil {
+			logrus.Fatalf("%s", err.Error())
+			os.Exit(1)
+		}
+
+		displayChanges(result.Changes)
+
+		unencryptedKeyData := result.UnencryptedKeyData
+		if len(deliveryFile) > 0 {
+			if err := writeDelivery(os.Stdout, deliveryFile, []generate.GeneratedKey{{
+				CRD:                result.Successor,
+				UnencryptedKeyData: unencryptedKeyData,
+				Entropy:            result.Entropy,
+			}}, recipients); err != nil {
+				logrus.Fatalf("%s", err.Error())
+				os.Exit(1)
+			}
+			unencryptedKeyData = nil
+		}
+
+		if err := generate.Display(os.Stdout, false, unencryptedKeyData, result.Entropy, result.Successor); err != nil {
+			logrus.Fatalf("%s", err.Error())
+			os.Exit(1)
+		}
+
+		os.Exit(0)
+	},
+}
+
+func displayChanges(changes []rotate.Change) {
+	for _, change := range changes {
+		fmt.Printf("%s: %s\n", change.File, change.Description)
+	}
+}
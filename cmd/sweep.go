@@ -0,0 +1,151 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/northwesternmutual/kanali/controller"
+	"github.com/northwesternmutual/kanalictl/config"
+	"github.com/northwesternmutual/kanalictl/pkg/cluster"
+	"github.com/northwesternmutual/kanalictl/pkg/expiry"
+	"github.com/northwesternmutual/kanalictl/pkg/rotate"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	sweepCmd.Flags().StringP(config.FlagKeyInFile.Long, "f", config.FlagKeyInFile.Value.(string), config.FlagKeyInFile.Usage)
+	sweepCmd.Flags().BoolP(config.FlagFromCluster.Long, config.FlagFromCluster.Short, config.FlagFromCluster.Value.(bool), config.FlagFromCluster.Usage)
+	sweepCmd.Flags().StringP(config.FlagClusterNamespace.Long, config.FlagClusterNamespace.Short, config.FlagClusterNamespace.Value.(string), config.FlagClusterNamespace.Usage)
+	sweepCmd.Flags().BoolP(config.FlagAllNamespaces.Long, config.FlagAllNamespaces.Short, config.FlagAllNamespaces.Value.(bool), config.FlagAllNamespaces.Usage)
+	sweepCmd.Flags().BoolP(config.FlagSweepDryRun.Long, config.FlagSweepDryRun.Short, config.FlagSweepDryRun.Value.(bool), config.FlagSweepDryRun.Usage)
+
+	apiKeyCmd.AddCommand(sweepCmd)
+}
+
+var sweepCmd = &cobra.Command{
+	Use:   `sweep`,
+	Short: `Removes expired API keys`,
+	Long: `Removes expired API keys. Each expired API key is removed from every
+ApiKeyBinding that references it and then deleted, either from files or, with
+--from-cluster, from the cluster.`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		if err := bindFlags(cmd, config.FlagKeyInFile, config.FlagFromCluster, config.FlagClusterNamespace, config.FlagAllNamespaces); err != nil {
+			logrus.Fatalf("%s", err.Error())
+			os.Exit(1)
+		}
+		if err := bindFlag(cmd, config.FlagSweepDryRun, config.KeySweepDryRun); err != nil {
+			logrus.Fatalf("%s", err.Error())
+			os.Exit(1)
+		}
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+
+		keys, err := getLocatedKeys()
+		if err != nil {
+			logrus.Fatalf("%s", err.Error())
+			os.Exit(1)
+		}
+
+		expired := expiry.Expired(expiry.Report(keys, time.Now(), 0, 0))
+		if len(expired) < 1 {
+			fmt.Println("no expired API keys found")
+			os.Exit(0)
+		}
+
+		if viper.GetBool(config.KeySweepDryRun) {
+			expiry.Render(os.Stdout, expired)
+			os.Exit(0)
+		}
+
+		if !viper.GetBool(config.FlagFromCluster.GetLong()) {
+			targets := make([]rotate.Key, 0, len(expired))
+			for _, entry := range expired {
+				targets = append(targets, rotate.Key{Namespace: entry.Namespace, Name: entry.Name})
+			}
+			changes, err := rotate.Remove(viper.GetString(config.FlagKeyInFile.GetLong()), targets...)
+			if err != nil {
+				logrus.Fatalf("%s", err.Error())
+				os.Exit(1)
+			}
+			displayChanges(changes)
+			os.Exit(0)
+		}
+
+		if err := sweepCluster(expired); err != nil {
+			logrus.Fatalf("%s", err.Error())
+			os.Exit(1)
+		}
+
+		os.Exit(0)
+	},
+}
+
+// sweepCluster removes expired API keys from every ApiKeyBinding, in any
+// namespace since API keys are referenced by name only, before deleting them
+// so that no binding references a missing key. An ApiKeyBinding left without
+// API keys is deleted, since an ApiKeyBinding must grant at least one API key
+// access.
+func sweepCluster(expired []expiry.Entry) error {
+	ctlr, err := controller.New()
+	if err != nil {
+		return err
+	}
+	client := cluster.NewClient(ctlr.RestClient.Client, ctlr.MasterHost)
+
+	names := make([]string, 0, len(expired))
+	for _, entry := range expired {
+		names = append(names, entry.Name)
+	}
+
+	bindings, err := cluster.APIKeyBindings(ctlr.RestClient.Client, ctlr.MasterHost, "")
+	if err != nil {
+		return err
+	}
+	unbound := expiry.Unbind(bindings, names)
+
+	for _, binding := range unbound {
+		if len(binding.Spec.Keys) < 1 {
+			if err := client.Delete(&cluster.Object{Kind: "ApiKeyBinding", Name: binding.ObjectMeta.Name, Namespace: binding.ObjectMeta.Namespace}); err != nil {
+				return err
+			}
+			fmt.Printf("deleted ApiKeyBinding %s in namespace %s, which only referenced expired API keys\n", binding.ObjectMeta.Name, binding.ObjectMeta.Namespace)
+			continue
+		}
+		if err := client.UpdateBinding(binding); err != nil {
+			return err
+		}
+		fmt.Printf("ApiKeyBinding %s in namespace %s no longer references expired API keys\n", binding.ObjectMeta.Name, binding.ObjectMeta.Namespace)
+	}
+
+	for _, entry := range expired {
+		if err := client.Delete(&cluster.Object{Kind: "ApiKey", Name: entry.Name, Namespace: entry.Namespace}); err != nil {
+			return err
+		}
+		fmt.Printf("deleted ApiKey %s in namespace %s\n", entry.Name, entry.Namespace)
+	}
+
+	return nil
+}
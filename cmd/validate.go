@@ -0,0 +1,63 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/northwesternmutual/kanalictl/config"
+	"github.com/northwesternmutual/kanalictl/controller"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	validateCmd.Flags().StringArrayP("file", "f", []string{}, "location to configuration file, directory or glob pattern, or - for stdin. May be repeated.")
+	validateCmd.Flags().BoolP(config.FlagRecursive.Long, config.FlagRecursive.Short, config.FlagRecursive.Value.(bool), config.FlagRecursive.Usage)
+	validateCmd.Flags().BoolP(config.FlagAgainstCluster.Long, config.FlagAgainstCluster.Short, config.FlagAgainstCluster.Value.(bool), config.FlagAgainstCluster.Usage)
+	RootCmd.AddCommand(validateCmd)
+}
+
+var validateCmd = &cobra.Command{
+	Use:   `validate`,
+	Short: `Validate resources by filename.`,
+	Long: `Validate resources by filename without creating or applying them.
+
+Paths and proxy names are only checked for uniqueness among the given files,
+so no cluster is needed, unless --against-cluster is given. Then they are
+also checked against the resources in the cluster, which must hold any
+ApiProxy or ApiKey an ApiKeyBinding references that is not in the files.`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		if err := bindFlags(cmd, config.FlagRecursive, config.FlagAgainstCluster); err != nil {
+			fmt.Print(err.Error())
+			os.Exit(1)
+		}
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		inputs, err := cmd.Flags().GetStringArray("file")
+		if err != nil {
+			fmt.Print(err.Error())
+			os.Exit(1)
+		}
+		os.Exit(controller.Validate(inputs, viper.GetBool(config.FlagRecursive.GetLong()), viper.GetBool(config.FlagAgainstCluster.GetLong())))
+	},
+}
@@ -0,0 +1,137 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package config
+
+import (
+	"github.com/northwesternmutual/kanali/config"
+	"github.com/northwesternmutual/kanalictl/pkg/cluster"
+)
+
+// KeyDryRun is the configuration key of FlagDryRun.
+const KeyDryRun = "apply.dry_run"
+
+var (
+	// FlagFromCluster specifies that resources are read from the cluster instead of from files.
+	FlagFromCluster = config.Flag{
+		Long:  "from-cluster",
+		Short: "",
+		Value: false,
+		Usage: "Read resources from the cluster instead of from files.",
+	}
+	// FlagClusterNamespace specifies the namespace resources are read from.
+	FlagClusterNamespace = config.Flag{
+		Long:  "namespace",
+		Short: "n",
+		Value: cluster.DefaultNamespace,
+		Usage: "Namespace resources are read from when using --from-cluster.",
+	}
+	// FlagAllNamespaces specifies that resources are read from every namespace.
+	FlagAllNamespaces = config.Flag{
+		Long:  "all-namespaces",
+		Short: "",
+		Value: false,
+		Usage: "Read resources from every namespace when using --from-cluster.",
+	}
+	// FlagUseKubectl specifies that resources are changed by running kubectl.
+	FlagUseKubectl = config.Flag{
+		Long:  "kubectl",
+		Short: "",
+		Value: false,
+		Usage: "Create or apply resources by running kubectl instead of calling the Kubernetes API.",
+	}
+	// FlagAtomic specifies that every change is reverted if any fails.
+	FlagAtomic = config.Flag{
+		Long:  "atomic",
+		Short: "",
+		Value: false,
+		Usage: "Revert every change made if any resource cannot be applied.",
+	}
+	// FlagDryRun specifies that resources are validated without being changed.
+	FlagDryRun = config.Flag{
+		Long:  "dry-run",
+		Short: "",
+		Value: "none",
+		Usage: "Must be none, client or server. client validates resources and prints them without sending them. server sends them to be validated by the API server without being persisted and requires Kubernetes 1.13 or later.",
+	}
+	// FlagDeleteNamespace specifies the namespace of resources deleted by name.
+	FlagDeleteNamespace = config.Flag{
+		Long:  "namespace",
+		Short: "n",
+		Value: cluster.DefaultNamespace,
+		Usage: "Namespace of the resources named on the command line.",
+	}
+	// FlagCascade specifies that resources referencing a deleted resource are changed to no longer reference it.
+	FlagCascade = config.Flag{
+		Long:  "cascade",
+		Short: "",
+		Value: false,
+		Usage: "Delete the ApiKeyBinding of a deleted ApiProxy and remove deleted API keys from the ApiKeyBindings that reference them.",
+	}
+	// FlagForce specifies that resources are deleted even if they are still referenced.
+	FlagForce = config.Flag{
+		Long:  "force",
+		Short: "",
+		Value: false,
+		Usage: "Delete resources even if they are still referenced, leaving the references behind.",
+	}
+	// FlagAgainstCluster specifies that resources are validated against those in the cluster.
+	FlagAgainstCluster = config.Flag{
+		Long:  "against-cluster",
+		Short: "",
+		Value: false,
+		Usage: "Check paths, proxy names and references against the resources in the cluster as well as the local files.",
+	}
+	// FlagPrune specifies that applied resources missing from the configuration are deleted.
+	FlagPrune = config.Flag{
+		Long:  "prune",
+		Short: "",
+		Value: false,
+		Usage: "Label applied resources with --selector and delete the ApiKeys, ApiProxies and ApiKeyBindings with those labels that were not applied.",
+	}
+	// FlagSelector specifies the labels of the resources that are pruned.
+	FlagSelector = config.Flag{
+		Long:  "selector",
+		Short: "l",
+		Value: "",
+		Usage: "Label selector of the form key=value[,key=value] that applied resources are labeled with and pruned by.",
+	}
+	// FlagPruneDryRun specifies that what would be pruned is printed without deleting anything.
+	FlagPruneDryRun = config.Flag{
+		Long:  "prune-dry-run",
+		Short: "",
+		Value: false,
+		Usage: "Print what --prune would delete without deleting anything.",
+	}
+	// FlagPruneNamespace specifies the only namespace that is pruned.
+	FlagPruneNamespace = config.Flag{
+		Long:  "namespace",
+		Short: "n",
+		Value: "",
+		Usage: "Only prune resources in this namespace. Defaults to the namespaces of the applied resources.",
+	}
+	// FlagRecursive specifies that directories are read recursively.
+	FlagRecursive = config.Flag{
+		Long:  "recursive",
+		Short: "R",
+		Value: false,
+		Usage: "Read the files in directories given with -f and in their subdirectories.",
+	}
+)
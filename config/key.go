@@ -21,7 +21,24 @@
 package config
 
 import (
+	"runtime"
+
 	"github.com/northwesternmutual/kanali/config"
+	"github.com/northwesternmutual/kanalictl/pkg/cluster"
+	"github.com/northwesternmutual/kanalictl/pkg/generate"
+)
+
+// Configuration keys of flags whose names differ from their configuration
+// keys.
+const (
+	// KeyNamespace is the configuration key of FlagKeyNamespace.
+	KeyNamespace = "key.namespace"
+	// KeyLabels is the configuration key of FlagKeyLabels.
+	KeyLabels = "key.labels"
+	// KeyAnnotations is the configuration key of FlagKeyAnnotations.
+	KeyAnnotations = "key.annotations"
+	// KeySweepDryRun is the configuration key of FlagSweepDryRun.
+	KeySweepDryRun = "sweep.dry_run"
 )
 
 var (
@@ -60,4 +77,172 @@ var (
 		Value: 32,
 		Usage: "Existing API key data.",
 	}
+	// FlagKeyAlphabet specifies the characters generated API keys are drawn from.
+	FlagKeyAlphabet = config.Flag{
+		Long:  "key.alphabet",
+		Short: "",
+		Value: generate.DefaultAlphabet,
+		Usage: "Characters generated API keys are drawn from.",
+	}
+	// FlagKeyMinEntropy specifies the minimum entropy, in bits, an API key must have.
+	FlagKeyMinEntropy = config.Flag{
+		Long:  "key.min_entropy",
+		Short: "",
+		Value: float64(generate.DefaultMinEntropy),
+		Usage: "Minimum entropy, in bits, an API key must have.",
+	}
+	// FlagKeyRosterFile specifies path to a roster of API keys to generate.
+	FlagKeyRosterFile = config.Flag{
+		Long:  "from-file",
+		Short: "",
+		Value: "",
+		Usage: "Path to a CSV or YAML roster of API keys to generate.",
+	}
+	// FlagKeyDeliveryFile specifies path to which unencrypted API keys are written.
+	FlagKeyDeliveryFile = config.Flag{
+		Long:  "key.delivery_file",
+		Short: "",
+		Value: "",
+		Usage: "Path to which unencrypted API keys are written instead of being displayed. Required when generating from a roster.",
+	}
+	// FlagKeyAgeRecipients specifies age recipients the delivery file is encrypted for.
+	FlagKeyAgeRecipients = config.Flag{
+		Long:  "age-recipient",
+		Short: "",
+		Value: []string{},
+		Usage: "age public key, or file of age public keys, the delivery file is encrypted for. May be repeated.",
+	}
+	// FlagKeyPGPRecipients specifies OpenPGP public key files the delivery file is encrypted for.
+	FlagKeyPGPRecipients = config.Flag{
+		Long:  "pgp-recipient",
+		Short: "",
+		Value: []string{},
+		Usage: "OpenPGP public key file the delivery file is encrypted for. May be repeated.",
+	}
+	// FlagKeyAllowPlaintext specifies whether unencrypted API keys may be displayed when stdout is not a terminal.
+	FlagKeyAllowPlaintext = config.Flag{
+		Long:  "allow-plaintext",
+		Short: "",
+		Value: false,
+		Usage: "Allow unencrypted API keys to be displayed when stdout is not a terminal.",
+	}
+	// FlagRotateFinalize specifies that the rotation of an API key should be completed.
+	FlagRotateFinalize = config.Flag{
+		Long:  "finalize",
+		Short: "",
+		Value: false,
+		Usage: "Remove the rotated API key from every binding and delete it.",
+	}
+	// FlagRotateNamespace specifies the namespace of the API key to rotate.
+	FlagRotateNamespace = config.Flag{
+		Long:  "rotate.namespace",
+		Short: "n",
+		Value: cluster.DefaultNamespace,
+		Usage: "Namespace of the API key to rotate.",
+	}
+	// FlagKeyNamespace specifies the namespace of generated API keys.
+	FlagKeyNamespace = config.Flag{
+		Long:  "namespace",
+		Short: "n",
+		Value: cluster.DefaultNamespace,
+		Usage: "Namespace of generated API keys.",
+	}
+	// FlagKeyLabels specifies labels of generated API keys.
+	FlagKeyLabels = config.Flag{
+		Long:  "label",
+		Short: "",
+		Value: []string{},
+		Usage: "Label, in the form key=value, to add to generated API keys. May be repeated.",
+	}
+	// FlagKeyAnnotations specifies annotations of generated API keys.
+	FlagKeyAnnotations = config.Flag{
+		Long:  "annotation",
+		Short: "",
+		Value: []string{},
+		Usage: "Annotation, in the form key=value, to add to generated API keys. May be repeated.",
+	}
+	// FlagKeyOverwrite specifies what happens when the out file already exists.
+	FlagKeyOverwrite = config.Flag{
+		Long:  "overwrite",
+		Short: "",
+		Value: "prompt",
+		Usage: "What to do when the out file already exists: prompt, force, never or append. Use - as the out file to write to stdout.",
+	}
+	// FlagKeyExpiresIn specifies how long until generated API keys expire.
+	FlagKeyExpiresIn = config.Flag{
+		Long:  "expires-in",
+		Short: "",
+		Value: "",
+		Usage: "How long until generated API keys expire, e.g. 90d. API keys never expire by default.",
+	}
+	// FlagKeyOwner specifies who owns generated API keys.
+	FlagKeyOwner = config.Flag{
+		Long:  "owner",
+		Short: "",
+		Value: "",
+		Usage: "Owner of generated API keys.",
+	}
+	// FlagKeyContact specifies how to reach the owner of generated API keys.
+	FlagKeyContact = config.Flag{
+		Long:  "contact",
+		Short: "",
+		Value: "",
+		Usage: "How to reach the owner of generated API keys, e.g. an email address.",
+	}
+	// FlagExpiringWithin specifies how soon API keys must expire to be reported.
+	FlagExpiringWithin = config.Flag{
+		Long:  "within",
+		Short: "",
+		Value: "30d",
+		Usage: "Report API keys that expire within this long, e.g. 30d.",
+	}
+	// FlagExpiringRotationAge specifies how old API keys must be to be reported.
+	FlagExpiringRotationAge = config.Flag{
+		Long:  "rotation-age",
+		Short: "",
+		Value: "",
+		Usage: "Also report API keys older than this, e.g. 180d.",
+	}
+	// FlagSweepDryRun specifies that expired API keys should only be reported.
+	FlagSweepDryRun = config.Flag{
+		Long:  "dry-run",
+		Short: "",
+		Value: false,
+		Usage: "Report which expired API keys would be removed without removing them.",
+	}
+	// FlagDecryptOutput specifies the format decrypted API keys are written in.
+	FlagDecryptOutput = config.Flag{
+		Long:  "output",
+		Short: "o",
+		Value: "table",
+		Usage: "Format decrypted API keys are written in: table, json, yaml, csv or env.",
+	}
+	// FlagDecryptNames specifies the names of the API keys to decrypt.
+	FlagDecryptNames = config.Flag{
+		Long:  "name",
+		Short: "",
+		Value: []string{},
+		Usage: "Only decrypt API keys with this name. May be repeated.",
+	}
+	// FlagDecryptNamespace specifies the namespace of the API keys to decrypt.
+	FlagDecryptNamespace = config.Flag{
+		Long:  "namespace",
+		Short: "n",
+		Value: "",
+		Usage: "Only decrypt API keys in this namespace. Defaults to the default namespace when using --from-cluster.",
+	}
+	// FlagDecryptConcurrency specifies how many API keys are decrypted at once.
+	FlagDecryptConcurrency = config.Flag{
+		Long:  "concurrency",
+		Short: "",
+		Value: runtime.NumCPU(),
+		Usage: "Number of API keys to decrypt at once.",
+	}
+	// FlagAuditMinLength specifies the minimum length of audited API keys.
+	FlagAuditMinLength = config.Flag{
+		Long:  "min-length",
+		Short: "",
+		Value: 16,
+		Usage: "Minimum length, in characters, of audited API keys.",
+	}
 )
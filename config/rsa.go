@@ -22,6 +22,7 @@ package config
 
 import (
 	"github.com/northwesternmutual/kanali/config"
+	"github.com/northwesternmutual/kanalictl/pkg/cluster"
 )
 
 var (
@@ -39,4 +40,60 @@ var (
 		Value: "",
 		Usage: "Path to RSA private key.",
 	}
+	// FlagRewrapOldPrivateKeyFile specifies path to the RSA private key API keys are currently encrypted with.
+	FlagRewrapOldPrivateKeyFile = config.Flag{
+		Long:  "old-private-key",
+		Short: "",
+		Value: "",
+		Usage: "Path to RSA private key API keys are currently encrypted with.",
+	}
+	// FlagRewrapNewPublicKeyFile specifies path to the RSA public key API keys will be encrypted with.
+	FlagRewrapNewPublicKeyFile = config.Flag{
+		Long:  "new-public-key",
+		Short: "",
+		Value: "",
+		Usage: "Path to RSA public key API keys will be encrypted with.",
+	}
+	// FlagRewrapCheck specifies that API keys should not be rewritten.
+	FlagRewrapCheck = config.Flag{
+		Long:  "check",
+		Short: "",
+		Value: false,
+		Usage: "Report which files would change without writing them.",
+	}
+	// FlagRSABits specifies the size of generated RSA keys.
+	FlagRSABits = config.Flag{
+		Long:  "bits",
+		Short: "",
+		Value: 4096,
+		Usage: "Size, in bits, of the generated RSA key. Must be at least 2048.",
+	}
+	// FlagRSASecretFile specifies path to which the Kubernetes Secret containing the RSA private key is written.
+	FlagRSASecretFile = config.Flag{
+		Long:  "secret.out_file",
+		Short: "",
+		Value: "kanali-decryption-key.yaml",
+		Usage: "Path to which the Kubernetes Secret containing the RSA private key is written, readable only by its owner. Use - for stdout.",
+	}
+	// FlagRSASecretName specifies the name of the Kubernetes Secret containing the RSA private key.
+	FlagRSASecretName = config.Flag{
+		Long:  "secret.name",
+		Short: "",
+		Value: "kanali-decryption-key",
+		Usage: "Name of the Kubernetes Secret containing the RSA private key.",
+	}
+	// FlagRSASecretNamespace specifies the namespace of the Kubernetes Secret containing the RSA private key.
+	FlagRSASecretNamespace = config.Flag{
+		Long:  "secret.namespace",
+		Short: "",
+		Value: cluster.DefaultNamespace,
+		Usage: "Namespace of the Kubernetes Secret containing the RSA private key. Must be the namespace Kanali runs in.",
+	}
+	// FlagRSASecretKey specifies the key under which the RSA private key is stored in the Kubernetes Secret.
+	FlagRSASecretKey = config.Flag{
+		Long:  "secret.key",
+		Short: "",
+		Value: "key.pem",
+		Usage: "Key under which the RSA private key is stored in the Kubernetes Secret.",
+	}
 )
@@ -21,95 +21,387 @@
 package controller
 
 import (
-	"bufio"
-	"bytes"
 	"errors"
 	"fmt"
-	"io"
 	"io/ioutil"
 	"os"
-	"path/filepath"
+	"strings"
 
 	"github.com/ghodss/yaml"
 	"github.com/northwesternmutual/kanali/controller"
 	"github.com/northwesternmutual/kanali/spec"
+	"github.com/northwesternmutual/kanalictl/pkg/cluster"
+	"github.com/northwesternmutual/kanalictl/pkg/manifest"
 	"github.com/northwesternmutual/kanalictl/utils"
 	"github.com/northwesternmutual/kanalictl/validation"
-	k8sYaml "k8s.io/apimachinery/pkg/util/yaml"
 	"k8s.io/kubernetes/pkg/api/unversioned"
 )
 
-// CreateOrApply validates a spec and then performs either a create or apply
-func CreateOrApply(op, path string) int {
+// document is a single resource configuration and where it was read from.
+type document struct {
+	source string
+	index  int
+	data   []byte
+	obj    *cluster.Object
+}
+
+const (
+	// DryRunNone creates or applies resources.
+	DryRunNone = "none"
+	// DryRunClient validates resources and prints them without sending them.
+	DryRunClient = "client"
+	// DryRunServer sends resources to the API server to be admitted and
+	// validated without being persisted.
+	DryRunServer = "server"
+)
+
+// Options control how documents are created or applied.
+type Options struct {
+	// UseKubectl runs kubectl for each document instead of calling the
+	// Kubernetes API.
+	UseKubectl bool
+	// Atomic reverts every change made if any document fails to be
+	// created or applied.
+	Atomic bool
+	// Recursive reads the files in directories given as inputs, and in
+	// their subdirectories.
+	Recursive bool
+	// DryRun is DryRunNone, DryRunClient or DryRunServer. An empty value
+	// is the same as DryRunNone.
+	DryRun string
+	// Prune labels every applied resource with Selector and then deletes
+	// the resources with those labels that were not applied, in the
+	// namespaces of the applied resources or in PruneNamespace.
+	Prune bool
+	// Selector is a label selector of the form key=value[,key=value].
+	Selector string
+	// PruneDryRun prints what would be pruned without deleting anything.
+	PruneDryRun bool
+	// PruneNamespace is the only namespace pruned, if it is not empty.
+	PruneNamespace string
+}
+
+// change is a resource changed by a batch along with its state before the
+// change. The prior state is nil if the resource was created.
+type change struct {
+	obj   *cluster.Object
+	prior map[string]interface{}
+}
+
+// batch holds what is needed to validate and submit a set of documents.
+type batch struct {
+	ctlr    *controller.Controller
+	api     *cluster.Client
+	journal []change
+
+	// resources are what paths and proxy names must be unique among. If
+	// nil, they are listed from the cluster when first needed.
+	resources validation.Resources
+
+	// defined, paths and proxies record which document defines each
+	// resource, ApiProxy path and ApiKeyBinding proxy so that documents
+	// that conflict with each other are found before any is submitted.
+	defined map[string]*document
+	paths   map[string]*document
+	proxies map[string]*document
+}
+
+// CreateOrApply validates a spec and then performs either a create or apply.
+// Every document is validated before any is submitted so that an invalid
+// document leaves the cluster untouched. Documents are submitted in
+// dependency order, so that an ApiKeyBinding follows the resources it
+// references. Resources are created or applied through the Kubernetes API
+// unless the options say to run kubectl. A client dry run prints the
+// validated resources instead of submitting them and a server dry run
+// submits them to be validated without being persisted. Applied resources
+// are pruned if the options say to, as described by batch.prune.
+func CreateOrApply(op string, inputs []string, opts Options) int {
 
 	// check if file was passed in
-	if path == "" {
+	if len(inputs) < 1 {
 		fmt.Println("file must be specified")
 		return 1
 	}
 
-	// turn potential relative path into absolute path
-	absPath, err := filepath.Abs(path)
+	if opts.Atomic && opts.UseKubectl {
+		fmt.Println("changes made by kubectl cannot be rolled back - --atomic cannot be used with --kubectl")
+		return 1
+	}
+
+	switch opts.DryRun {
+	case "", DryRunNone:
+		opts.DryRun = DryRunNone
+	case DryRunClient, DryRunServer:
+		if opts.Atomic {
+			fmt.Println("a dry run changes nothing to roll back - --atomic cannot be used with --dry-run")
+			return 1
+		}
+		if opts.UseKubectl && opts.DryRun == DryRunServer {
+			fmt.Println("--dry-run=server cannot be used with --kubectl")
+			return 1
+		}
+	default:
+		fmt.Printf("invalid --dry-run value %q - must be none, client or server\n", opts.DryRun)
+		return 1
+	}
+
+	labels, err := pruneLabels(op, opts)
 	if err != nil {
 		fmt.Println(err.Error())
 		return 1
 	}
 
-	// attempt to parse YAML file
-	yamlData, err := ioutil.ReadFile(absPath)
+	docs, err := readDocuments(inputs, opts.Recursive)
 	if err != nil {
-		fmt.Println("file is not a valid YAML file")
+		fmt.Println(err.Error())
 		return 1
 	}
 
-	yamlReader := k8sYaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(yamlData)))
+	b := newBatch()
 
-	var document []byte
-	document, err = yamlReader.Read()
-	if err != nil {
-		fmt.Println("could not read yaml file")
+	problems := []string{}
+	for _, doc := range docs {
+		if err := b.validate(doc); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", doc, err.Error()))
+		}
+	}
+	if len(problems) > 0 {
+		fmt.Printf("%d of %d documents are invalid - no resources were changed:\n%s\n", len(problems), len(docs), strings.Join(problems, "\n"))
+		return 1
+	}
+
+	docs, problems = b.order(docs)
+	if len(problems) > 0 {
+		fmt.Printf("could not order the resources to submit - no resources were changed:\n%s\n", strings.Join(problems, "\n"))
 		return 1
 	}
 
-	for {
-		msg, code := handleDocument(document, op)
+	if err := label(docs, labels); err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	if opts.DryRun == DryRunClient {
+		if code := printDocuments(docs); code != 0 || !opts.Prune {
+			return code
+		}
+		return b.prune(docs, labels, opts.PruneNamespace, true)
+	}
+
+	if opts.DryRun == DryRunServer {
+		client, err := b.client()
+		if err != nil {
+			fmt.Println(err.Error())
+			return 1
+		}
+		if err := client.CheckDryRun(); err != nil {
+			fmt.Printf("%s - no resources were changed\n", err.Error())
+			return 1
+		}
+	}
+
+	for _, doc := range docs {
+		msg, code := b.submit(doc, op, opts)
 		if code != 0 {
 			fmt.Println(msg)
+			if opts.Atomic {
+				b.rollback()
+			}
 			return code
 		}
 		fmt.Print(msg)
-		document, err = yamlReader.Read()
-		if err != nil && err != io.EOF {
-			fmt.Println("could not read yaml file")
+	}
+
+	if opts.Prune {
+		return b.prune(docs, labels, opts.PruneNamespace, opts.PruneDryRun || opts.DryRun != DryRunNone)
+	}
+
+	return 0
+
+}
+
+// printDocuments prints the resources of documents as they would be sent
+// to the cluster.
+func printDocuments(docs []*document) int {
+	for i, doc := range docs {
+		fields, err := doc.obj.Fields()
+		if err != nil {
+			fmt.Println(err.Error())
+			return 1
+		}
+		data, err := yaml.Marshal(fields)
+		if err != nil {
+			fmt.Println(err.Error())
 			return 1
-		} else if err != nil && err == io.EOF {
-			return 0
 		}
+		if i > 0 {
+			fmt.Println("---")
+		}
+		fmt.Print(string(data))
 	}
+	return 0
+}
 
+func newBatch() *batch {
+	return &batch{
+		defined: map[string]*document{},
+		paths:   map[string]*document{},
+		proxies: map[string]*document{},
+	}
 }
 
-func handleDocument(data []byte, op string) (string, int) {
-	kind, err := getKind(data)
+// readDocuments reads every non empty document in the files named by
+// inputs. See manifest.Expand for the inputs that are accepted.
+func readDocuments(inputs []string, recursive bool) ([]*document, error) {
+
+	files, err := manifest.Load(inputs, recursive)
 	if err != nil {
-		return err.Error(), 1
+		return nil, err
+	}
+
+	docs := []*document{}
+	for _, f := range files {
+		for _, doc := range f.Documents {
+			if doc.Empty() {
+				continue
+			}
+			docs = append(docs, &document{source: f.Path, index: doc.Index, data: doc.Data})
+		}
+	}
+
+	if len(docs) < 1 {
+		return nil, errors.New("no resources were found")
+	}
+
+	return docs, nil
+
+}
+
+// parse parses the resource of a document, which must be a kind kanalictl
+// manages.
+func (d *document) parse() error {
+	kind, err := getKind(d.data)
+	if err != nil {
+		return err
 	}
 
 	switch kind {
+	case "ApiKey", "ApiProxy", "ApiKeyBinding":
+	default:
+		return errors.New("please use kubectl for this configuration file")
+	}
+
+	obj, err := cluster.ParseObject(d.data)
+	if err != nil {
+		return err
+	}
+
+	d.obj = obj
+	return nil
+}
+
+func (d *document) String() string {
+	if d.source == manifest.Stdin {
+		return fmt.Sprintf("stdin#%d", d.index)
+	}
+	return fmt.Sprintf("%s#%d", d.source, d.index)
+}
+
+// controller returns the controller used to reach the cluster, creating it
+// the first time it is needed.
+func (b *batch) controller() (*controller.Controller, error) {
+	if b.ctlr != nil {
+		return b.ctlr, nil
+	}
+
+	ctlr, err := controller.New()
+	if err != nil {
+		return nil, err
+	}
+
+	b.ctlr = ctlr
+	return ctlr, nil
+}
+
+// client returns a client for the Kubernetes API, creating it the first
+// time it is needed.
+func (b *batch) client() (*cluster.Client, error) {
+	if b.api != nil {
+		return b.api, nil
+	}
+
+	ctlr, err := b.controller()
+	if err != nil {
+		return nil, err
+	}
+
+	b.api = cluster.NewClient(ctlr.RestClient.Client, ctlr.MasterHost)
+	return b.api, nil
+}
+
+// existing returns the resources that paths and proxy names must be
+// unique among. The resources in the cluster are listed once and reused
+// for every document of the batch.
+func (b *batch) existing() (validation.Resources, error) {
+	if b.resources != nil {
+		return b.resources, nil
+	}
+
+	ctlr, err := b.controller()
+	if err != nil {
+		return nil, err
+	}
+
+	resources, err := list(validation.Cluster(ctlr.RestClient.Client, ctlr.MasterHost))
+	if err != nil {
+		return nil, err
+	}
+
+	b.resources = resources
+	return resources, nil
+}
+
+// list lists resources once, returning them as local resources.
+func list(resources validation.Resources) (validation.Resources, error) {
+	proxies, err := resources.APIProxies()
+	if err != nil {
+		return nil, err
+	}
+	bindings, err := resources.APIKeyBindings()
+	if err != nil {
+		return nil, err
+	}
+	return validation.Local(proxies, bindings), nil
+}
+
+// validate validates a single document, both on its own and against the
+// documents validated before it.
+func (b *batch) validate(doc *document) error {
+	if err := doc.parse(); err != nil {
+		return err
+	}
+	obj := doc.obj
+
+	id := objectID(obj.Kind, obj.Namespace, obj.Name)
+	if other, ok := b.defined[id]; ok {
+		return fmt.Errorf("%s in namespace %s is also defined at %s", obj, obj.Namespace, other)
+	}
+	b.defined[id] = doc
+
+	switch obj.Kind {
 	case "ApiKey":
-		if err := handleAPIKey(data); err != nil {
-			return err.Error(), 1
-		}
+		return handleAPIKey(doc.data)
 	case "ApiProxy":
-		if err := handleAPIProxy(data); err != nil {
-			return err.Error(), 1
-		}
-	case "ApiKeyBinding":
-		if err := handleAPIKeyBinding(data); err != nil {
-			return err.Error(), 1
-		}
+		return b.handleAPIProxy(doc)
 	default:
-		return "please use kubectl for this configuration file", 1
+		return b.handleAPIKeyBinding(doc)
+	}
+}
+
+// submit creates or applies the resource of a document.
+func (b *batch) submit(doc *document, op string, opts Options) (string, int) {
+	if !opts.UseKubectl {
+		return b.handleObject(doc.obj, op, opts)
 	}
 
 	tmpfile, err := ioutil.TempFile("", "kanalictl")
@@ -121,7 +413,7 @@ func handleDocument(data []byte, op string) (string, int) {
 			fmt.Printf("could not remove temp file %s", tmpfile.Name())
 		}
 	}()
-	if _, err := tmpfile.Write(data); err != nil {
+	if _, err := tmpfile.Write(doc.data); err != nil {
 		return err.Error(), 1
 	}
 	if err := tmpfile.Close(); err != nil {
@@ -131,36 +423,130 @@ func handleDocument(data []byte, op string) (string, int) {
 	return utils.Execute("kubectl", op, "-f", tmpfile.Name())
 }
 
-func handleAPIKeyBinding(data []byte) error {
+// handleObject creates or applies a resource through the Kubernetes API.
+// If the change may need to be rolled back, the state of the resource is
+// recorded before it is changed.
+func (b *batch) handleObject(obj *cluster.Object, op string, opts Options) (string, int) {
+	client, err := b.client()
+	if err != nil {
+		return err.Error(), 1
+	}
+	suffix := ""
+	if opts.DryRun == DryRunServer {
+		client = client.DryRun()
+		suffix = " (server dry run)"
+	}
+	record := opts.Atomic
+
+	var prior map[string]interface{}
+	if record {
+		if prior, err = client.Get(obj); err != nil {
+			return fmt.Sprintf("could not retrieve %s in namespace %s: %s", obj, obj.Namespace, err.Error()), 1
+		}
+	}
+
+	result := "created"
+	if op == "apply" {
+		result, err = client.Apply(obj)
+	} else {
+		err = client.Create(obj)
+	}
+	if err != nil {
+		return fmt.Sprintf("could not %s %s in namespace %s: %s", op, obj, obj.Namespace, err.Error()), 1
+	}
+
+	if record && result != "unchanged" {
+		b.journal = append(b.journal, change{obj: obj, prior: prior})
+	}
+
+	return fmt.Sprintf("%s %s%s\n", obj, result, suffix), 0
+}
+
+// rollback reverts every change recorded by the batch, most recent first,
+// deleting created resources and restoring changed ones.
+func (b *batch) rollback() {
+	if len(b.journal) < 1 {
+		fmt.Println("nothing to roll back - no resources were changed")
+		return
+	}
+
+	client, err := b.client()
+	if err != nil {
+		fmt.Printf("could not roll back: %s\n", err.Error())
+		return
+	}
+
+	fmt.Println("rolling back:")
+	failed := false
+	for i := len(b.journal) - 1; i >= 0; i-- {
+		c := b.journal[i]
+		if c.prior == nil {
+			err = client.Delete(c.obj)
+		} else {
+			err = client.Restore(c.obj, c.prior)
+		}
+		if err != nil {
+			fmt.Printf("could not revert %s in namespace %s: %s\n", c.obj, c.obj.Namespace, err.Error())
+			failed = true
+		} else if c.prior == nil {
+			fmt.Printf("%s in namespace %s deleted\n", c.obj, c.obj.Namespace)
+		} else {
+			fmt.Printf("%s in namespace %s restored\n", c.obj, c.obj.Namespace)
+		}
+	}
+
+	if failed {
+		fmt.Println("rollback incomplete - the resources above that could not be reverted must be fixed by hand")
+	}
+}
+
+func (b *batch) handleAPIKeyBinding(doc *document) error {
 	var binding spec.APIKeyBinding
 
-	err := yaml.Unmarshal(data, &binding)
+	err := yaml.Unmarshal(doc.data, &binding)
 	if err != nil {
 		return err
 	}
+	binding.ObjectMeta.Namespace = doc.obj.Namespace
 
-	ctlr, err := controller.New()
+	if len(binding.Spec.APIProxyName) > 0 {
+		proxy := binding.ObjectMeta.Namespace + "/" + binding.Spec.APIProxyName
+		if other, ok := b.proxies[proxy]; ok {
+			return fmt.Errorf("the ApiKeyBinding at %s is also for the ApiProxy %s. Only one ApiKeyBinding may exist per ApiProxy", other, binding.Spec.APIProxyName)
+		}
+		b.proxies[proxy] = doc
+	}
+
+	resources, err := b.existing()
 	if err != nil {
 		return err
 	}
 
-	return validation.ValidateAPIKeyBinding(binding, ctlr.RestClient.Client, ctlr.MasterHost)
+	return validation.ValidateAPIKeyBindingWith(binding, resources)
 }
 
-func handleAPIProxy(data []byte) error {
+func (b *batch) handleAPIProxy(doc *document) error {
 	var proxy spec.APIProxy
 
-	err := yaml.Unmarshal(data, &proxy)
+	err := yaml.Unmarshal(doc.data, &proxy)
 	if err != nil {
 		return err
 	}
+	proxy.ObjectMeta.Namespace = doc.obj.Namespace
 
-	ctlr, err := controller.New()
+	if len(proxy.Spec.Path) > 0 {
+		if other, ok := b.paths[proxy.Spec.Path]; ok {
+			return fmt.Errorf("the ApiProxy at %s has the same path. Paths must be unique", other)
+		}
+		b.paths[proxy.Spec.Path] = doc
+	}
+
+	resources, err := b.existing()
 	if err != nil {
 		return err
 	}
 
-	return validation.ValidateAPIProxy(proxy, ctlr.RestClient.Client, ctlr.MasterHost)
+	return validation.ValidateAPIProxyWith(proxy, resources)
 }
 
 func handleAPIKey(data []byte) error {
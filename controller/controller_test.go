@@ -0,0 +1,152 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package controller
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/northwesternmutual/kanalictl/pkg/cluster"
+	"github.com/northwesternmutual/kanalictl/validation"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadDocuments(t *testing.T) {
+	dir, err := ioutil.TempDir("", "controller")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "keys.yaml")
+	assert.Nil(t, ioutil.WriteFile(path, []byte("---\nkind: ApiKey\nmetadata:\n  name: foo\nspec:\n  data: ab\n---\n# nothing here\n---\nkind: ApiKey\nmetadata:\n  name: bar\nspec:\n  data: cd\n"), 0644))
+
+	docs, err := readDocuments([]string{path}, false)
+	assert.Nil(t, err)
+	assert.Equal(t, len(docs), 2)
+	assert.Equal(t, docs[0].String(), path+"#1")
+	assert.Equal(t, docs[1].String(), path+"#3")
+
+	assert.Nil(t, ioutil.WriteFile(path, []byte("---\n"), 0644))
+	_, err = readDocuments([]string{path}, false)
+	assert.Equal(t, err.Error(), "no resources were found")
+}
+
+func TestValidate(t *testing.T) {
+	b := newBatch()
+
+	docs := []*document{
+		{source: "a.yaml", index: 0, data: []byte("kind: ApiKey\nmetadata:\n  name: foo\nspec:\n  data: ab\n")},
+		{source: "a.yaml", index: 1, data: []byte("kind: ApiKey\nmetadata:\n  name: foo\n  namespace: default\nspec:\n  data: cd\n")},
+		{source: "a.yaml", index: 2, data: []byte("kind: ApiKey\nmetadata:\n  name: bar\nspec:\n  data: ''\n")},
+		{source: "a.yaml", index: 3, data: []byte("kind: Service\nmetadata:\n  name: baz\n")},
+	}
+
+	assert.Nil(t, b.validate(docs[0]))
+	assert.Equal(t, b.validate(docs[1]).Error(), `apikey "foo" in namespace default is also defined at a.yaml#0`)
+	assert.Equal(t, b.validate(docs[2]).Error(), "api key does not contain any data")
+	assert.Equal(t, b.validate(docs[3]).Error(), "please use kubectl for this configuration file")
+}
+
+func TestRollback(t *testing.T) {
+	requests := []string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		w.Write([]byte(`{"kind":"ApiProxy","metadata":{"name":"foo","resourceVersion":"2"}}`))
+	}))
+	defer ts.Close()
+
+	created, err := cluster.ParseObject([]byte("kind: ApiKey\nmetadata:\n  name: key\n"))
+	assert.Nil(t, err)
+	changed, err := cluster.ParseObject([]byte("kind: ApiProxy\nmetadata:\n  name: foo\n"))
+	assert.Nil(t, err)
+
+	b := &batch{
+		api: cluster.NewClient(http.DefaultClient, ts.URL),
+		journal: []change{
+			{obj: changed, prior: map[string]interface{}{"kind": "ApiProxy", "metadata": map[string]interface{}{"name": "foo", "resourceVersion": "1"}}},
+			{obj: created},
+		},
+	}
+	b.rollback()
+
+	assert.Equal(t, requests, []string{
+		"DELETE /apis/kanali.io/v1/namespaces/default/apikeys/key",
+		"GET /apis/kanali.io/v1/namespaces/default/apiproxies/foo",
+		"PUT /apis/kanali.io/v1/namespaces/default/apiproxies/foo",
+	})
+}
+
+func TestServerDryRun(t *testing.T) {
+	requests := []string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.RequestURI())
+		if r.Method == "GET" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	obj, err := cluster.ParseObject([]byte("kind: ApiProxy\nmetadata:\n  name: foo\n"))
+	assert.Nil(t, err)
+
+	b := &batch{api: cluster.NewClient(http.DefaultClient, ts.URL)}
+	msg, code := b.handleObject(obj, "apply", Options{DryRun: DryRunServer})
+	assert.Equal(t, code, 0)
+	assert.Equal(t, msg, "apiproxy \"foo\" created (server dry run)\n")
+	assert.Equal(t, requests, []string{
+		"GET /apis/kanali.io/v1/namespaces/default/apiproxies/foo",
+		"POST /apis/kanali.io/v1/namespaces/default/apiproxies?dryRun=All",
+	})
+
+	assert.Equal(t, CreateOrApply("apply", []string{"a.yaml"}, Options{DryRun: "local"}), 1)
+	assert.Equal(t, CreateOrApply("apply", []string{"a.yaml"}, Options{DryRun: DryRunClient, Atomic: true}), 1)
+}
+
+func TestExistingListedOnce(t *testing.T) {
+	requests := []string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.RequestURI())
+		w.Write([]byte(`{"items": []}`))
+	}))
+	defer ts.Close()
+
+	resources, err := list(validation.Cluster(http.DefaultClient, ts.URL))
+	assert.Nil(t, err)
+
+	b := newBatch()
+	b.resources = resources
+	docs := []*document{
+		{source: "a.yaml", index: 0, data: []byte("kind: ApiProxy\nmetadata:\n  name: foo\nspec:\n  path: /foo\n  target: /\n  service:\n    name: foo\n    port: 80\n")},
+		{source: "a.yaml", index: 1, data: []byte("kind: ApiProxy\nmetadata:\n  name: bar\nspec:\n  path: /bar\n  target: /\n  service:\n    name: bar\n    port: 80\n")},
+		{source: "a.yaml", index: 2, data: []byte("kind: ApiKeyBinding\nmetadata:\n  name: foo\nspec:\n  proxy: foo\n  keys:\n  - name: foo\n")},
+	}
+	for _, doc := range docs {
+		assert.Nil(t, b.validate(doc))
+	}
+
+	assert.Equal(t, len(requests), 2)
+}
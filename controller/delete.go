@@ -0,0 +1,319 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/northwesternmutual/kanali/spec"
+	"github.com/northwesternmutual/kanalictl/pkg/cluster"
+	"github.com/northwesternmutual/kanalictl/pkg/expiry"
+)
+
+// DeleteOptions control how resources are deleted.
+type DeleteOptions struct {
+	// Cascade deletes the ApiKeyBinding of every deleted ApiProxy and
+	// removes every deleted ApiKey from the ApiKeyBindings that reference
+	// it, deleting those left without API keys.
+	Cascade bool
+	// Force deletes resources that are still referenced.
+	Force bool
+	// Recursive reads the files in directories given as inputs, and in
+	// their subdirectories.
+	Recursive bool
+	// DryRun prints what would be deleted or updated without changing
+	// anything.
+	DryRun bool
+}
+
+// deletion is what must be done to delete a set of resources.
+type deletion struct {
+	// unbind are the ApiKeyBindings to update, without the deleted API
+	// keys, before any resource is deleted.
+	unbind []spec.APIKeyBinding
+	// objects are the resources to delete, in the order they are deleted.
+	objects []*cluster.Object
+	// dangling are the references left behind by a forced deletion.
+	dangling []string
+	// emptied are the ApiKeyBindings deleted by a cascade because every API
+	// key they grant access to is deleted.
+	emptied []string
+}
+
+// byDeleteOrder sorts resources so that nothing is deleted while a
+// resource that is deleted later may still reference it.
+type byDeleteOrder []*cluster.Object
+
+func (s byDeleteOrder) Len() int      { return len(s) }
+func (s byDeleteOrder) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byDeleteOrder) Less(i, j int) bool {
+	return deleteRank(s[i].Kind) < deleteRank(s[j].Kind)
+}
+
+// DeleteFiles deletes the resources configured in the files named by
+// inputs. See deleteObjects for how they are deleted.
+func DeleteFiles(inputs []string, opts DeleteOptions) int {
+
+	// check if file was passed in
+	if len(inputs) < 1 {
+		fmt.Println("file must be specified")
+		return 1
+	}
+
+	docs, err := readDocuments(inputs, opts.Recursive)
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	problems := []string{}
+	objs := []*cluster.Object{}
+	for _, doc := range docs {
+		if err := doc.parse(); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", doc, err.Error()))
+			continue
+		}
+		objs = append(objs, doc.obj)
+	}
+	if len(problems) > 0 {
+		fmt.Printf("%d of %d documents are invalid - no resources were deleted:\n%s\n", len(problems), len(docs), strings.Join(problems, "\n"))
+		return 1
+	}
+
+	return deleteObjects(objs, opts, deleteHint)
+
+}
+
+// DeleteNamed deletes the resources of a kind with the given names in a
+// namespace. The kind may be given the way kubectl accepts it, such as
+// apiproxy, apiproxies or ApiProxy. See deleteObjects for how they are
+// deleted.
+func DeleteNamed(kind string, names []string, namespace string, opts DeleteOptions) int {
+	kind, err := parseKind(kind)
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	if len(names) < 1 {
+		fmt.Printf("the name of the %s to delete must be specified\n", kind)
+		return 1
+	}
+
+	objs := make([]*cluster.Object, 0, len(names))
+	for _, name := range names {
+		objs = append(objs, &cluster.Object{Kind: kind, Name: name, Namespace: namespace})
+	}
+
+	return deleteObjects(objs, opts, deleteHint)
+}
+
+// deleteHint tells how to delete resources that are still referenced.
+const deleteHint = "use --cascade to delete or update what references them or --force to delete them anyway"
+
+// deleteObjects deletes resources after checking that each exists and that
+// no ApiKeyBinding left behind references any of them. ApiKeyBindings are
+// deleted first, then ApiProxies and then ApiKeys. If a check fails no
+// resource is deleted and hint is printed with the references found.
+func deleteObjects(objs []*cluster.Object, opts DeleteOptions, hint string) int {
+	b := newBatch()
+	client, err := b.client()
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	problems := []string{}
+	for _, obj := range objs {
+		current, err := client.Get(obj)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("could not retrieve %s in namespace %s: %s", obj, obj.Namespace, err.Error()))
+		} else if current == nil {
+			problems = append(problems, fmt.Sprintf("%s not found in namespace %s", obj, obj.Namespace))
+		}
+	}
+	if len(problems) > 0 {
+		fmt.Printf("no resources were deleted:\n%s\n", strings.Join(problems, "\n"))
+		return 1
+	}
+
+	bindings, err := cluster.APIKeyBindings(b.ctlr.RestClient.Client, b.ctlr.MasterHost, "")
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	plan, problems := planDeletion(objs, bindings, opts)
+	if len(problems) > 0 {
+		fmt.Printf("no resources were deleted - %s:\n%s\n", hint, strings.Join(problems, "\n"))
+		return 1
+	}
+	for _, msg := range plan.dangling {
+		fmt.Printf("warning: %s\n", msg)
+	}
+	for _, msg := range plan.emptied {
+		fmt.Println(msg)
+	}
+
+	for _, binding := range plan.unbind {
+		obj := &cluster.Object{Kind: "ApiKeyBinding", Name: binding.ObjectMeta.Name, Namespace: binding.ObjectMeta.Namespace}
+		if opts.DryRun {
+			fmt.Printf("%s in namespace %s no longer references the deleted API keys (dry run)\n", obj, obj.Namespace)
+			continue
+		}
+		if err := client.UpdateBinding(binding); err != nil {
+			fmt.Printf("could not update %s in namespace %s: %s\n", obj, obj.Namespace, err.Error())
+			return 1
+		}
+		fmt.Printf("%s in namespace %s no longer references the deleted API keys\n", obj, obj.Namespace)
+	}
+
+	for _, obj := range plan.objects {
+		if opts.DryRun {
+			fmt.Printf("%s deleted (dry run)\n", obj)
+			continue
+		}
+		if err := client.Delete(obj); err != nil {
+			fmt.Printf("could not delete %s in namespace %s: %s\n", obj, obj.Namespace, err.Error())
+			return 1
+		}
+		fmt.Printf("%s deleted\n", obj)
+	}
+
+	return 0
+}
+
+// planDeletion works out how to delete resources given every ApiKeyBinding
+// in the cluster. Each ApiKeyBinding that is not itself deleted must not
+// reference a deleted ApiProxy in its namespace or a deleted ApiKey of any
+// namespace, since API keys are referenced by name only. Such references
+// are returned as problems unless the options say to cascade or force.
+func planDeletion(objs []*cluster.Object, bindings []spec.APIKeyBinding, opts DeleteOptions) (*deletion, []string) {
+	plan := &deletion{}
+
+	deleting := map[string]bool{}
+	add := func(obj *cluster.Object) {
+		id := objectID(obj.Kind, obj.Namespace, obj.Name)
+		if !deleting[id] {
+			deleting[id] = true
+			plan.objects = append(plan.objects, obj)
+		}
+	}
+	for _, obj := range objs {
+		add(obj)
+	}
+
+	problems := []string{}
+	reference := func(obj *cluster.Object, binding spec.APIKeyBinding) {
+		msg := fmt.Sprintf("%s in namespace %s is referenced by apikeybinding %q in namespace %s", obj, obj.Namespace, binding.ObjectMeta.Name, binding.ObjectMeta.Namespace)
+		if opts.Force {
+			plan.dangling = append(plan.dangling, msg)
+		} else {
+			problems = append(problems, msg)
+		}
+	}
+
+	for _, obj := range objs {
+		if obj.Kind != "ApiProxy" {
+			continue
+		}
+		for _, binding := range bindings {
+			if binding.ObjectMeta.Namespace != obj.Namespace || binding.Spec.APIProxyName != obj.Name ||
+				deleting[objectID("ApiKeyBinding", binding.ObjectMeta.Namespace, binding.ObjectMeta.Name)] {
+				continue
+			}
+			if opts.Cascade {
+				add(&cluster.Object{Kind: "ApiKeyBinding", Name: binding.ObjectMeta.Name, Namespace: binding.ObjectMeta.Namespace})
+			} else {
+				reference(obj, binding)
+			}
+		}
+	}
+
+	names := []string{}
+	remaining := []spec.APIKeyBinding{}
+	for _, binding := range bindings {
+		if !deleting[objectID("ApiKeyBinding", binding.ObjectMeta.Namespace, binding.ObjectMeta.Name)] {
+			remaining = append(remaining, binding)
+		}
+	}
+	for _, obj := range objs {
+		if obj.Kind != "ApiKey" {
+			continue
+		}
+		names = append(names, obj.Name)
+		if opts.Cascade {
+			continue
+		}
+		for _, binding := range remaining {
+			for _, key := range binding.Spec.Keys {
+				if key.Name == obj.Name {
+					reference(obj, binding)
+					break
+				}
+			}
+		}
+	}
+	if opts.Cascade && len(names) > 0 {
+		for _, binding := range expiry.Unbind(remaining, names) {
+			// an ApiKeyBinding must grant at least one API key access
+			if len(binding.Spec.Keys) < 1 {
+				obj := &cluster.Object{Kind: "ApiKeyBinding", Name: binding.ObjectMeta.Name, Namespace: binding.ObjectMeta.Namespace}
+				add(obj)
+				plan.emptied = append(plan.emptied, fmt.Sprintf("%s in namespace %s is deleted since it only references deleted API keys", obj, obj.Namespace))
+				continue
+			}
+			plan.unbind = append(plan.unbind, binding)
+		}
+	}
+
+	sort.Stable(byDeleteOrder(plan.objects))
+	return plan, problems
+}
+
+// parseKind returns the kind of a resource type given the way kubectl
+// accepts it.
+func parseKind(resource string) (string, error) {
+	for _, kind := range []string{"ApiKey", "ApiProxy", "ApiKeyBinding"} {
+		if strings.EqualFold(resource, kind) || strings.EqualFold(resource, cluster.Resource(kind)) {
+			return kind, nil
+		}
+	}
+	return "", errors.New("resource type must be apikey, apiproxy or apikeybinding - please use kubectl for other resources")
+}
+
+func deleteRank(kind string) int {
+	switch kind {
+	case "ApiKeyBinding":
+		return 0
+	case "ApiProxy":
+		return 1
+	default:
+		return 2
+	}
+}
+
+func objectID(kind, namespace, name string) string {
+	return fmt.Sprintf("%s/%s/%s", kind, namespace, name)
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package controller
+
+import (
+	"testing"
+
+	"github.com/northwesternmutual/kanali/spec"
+	"github.com/northwesternmutual/kanalictl/pkg/cluster"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/kubernetes/pkg/api"
+)
+
+func TestPlanDeletion(t *testing.T) {
+	bindings := []spec.APIKeyBinding{
+		{
+			ObjectMeta: api.ObjectMeta{Name: "foo", Namespace: "default"},
+			Spec:       spec.APIKeyBindingSpec{APIProxyName: "foo", Keys: []spec.Key{{Name: "a"}, {Name: "b"}}},
+		},
+		{
+			ObjectMeta: api.ObjectMeta{Name: "bar", Namespace: "other"},
+			Spec:       spec.APIKeyBindingSpec{APIProxyName: "foo", Keys: []spec.Key{{Name: "a"}}},
+		},
+	}
+	key := &cluster.Object{Kind: "ApiKey", Name: "a", Namespace: "default"}
+	proxy := &cluster.Object{Kind: "ApiProxy", Name: "foo", Namespace: "default"}
+	binding := &cluster.Object{Kind: "ApiKeyBinding", Name: "foo", Namespace: "default"}
+
+	plan, problems := planDeletion([]*cluster.Object{key, proxy}, bindings, DeleteOptions{})
+	assert.Equal(t, problems, []string{
+		`apiproxy "foo" in namespace default is referenced by apikeybinding "foo" in namespace default`,
+		`apikey "a" in namespace default is referenced by apikeybinding "foo" in namespace default`,
+		`apikey "a" in namespace default is referenced by apikeybinding "bar" in namespace other`,
+	})
+
+	// the binding is deleted along with what it references and first
+	plan, problems = planDeletion([]*cluster.Object{key, proxy, binding}, bindings[:1], DeleteOptions{})
+	assert.Equal(t, len(problems), 0)
+	assert.Equal(t, plan.objects, []*cluster.Object{binding, proxy, key})
+
+	// a binding whose only key is deleted is deleted rather than left
+	// without keys
+	emptied := &cluster.Object{Kind: "ApiKeyBinding", Name: "bar", Namespace: "other"}
+	plan, problems = planDeletion([]*cluster.Object{key, proxy}, bindings, DeleteOptions{Cascade: true})
+	assert.Equal(t, len(problems), 0)
+	assert.Equal(t, plan.objects, []*cluster.Object{binding, emptied, proxy, key})
+	assert.Equal(t, len(plan.unbind), 0)
+	assert.Equal(t, plan.emptied, []string{`apikeybinding "bar" in namespace other is deleted since it only references deleted API keys`})
+
+	plan, problems = planDeletion([]*cluster.Object{key}, bindings, DeleteOptions{Cascade: true})
+	assert.Equal(t, len(problems), 0)
+	assert.Equal(t, plan.objects, []*cluster.Object{emptied, key})
+	assert.Equal(t, len(plan.unbind), 1)
+	assert.Equal(t, plan.unbind[0].ObjectMeta.Name, "foo")
+	assert.Equal(t, plan.unbind[0].Spec.Keys, []spec.Key{{Name: "b"}})
+
+	plan, problems = planDeletion([]*cluster.Object{key}, bindings, DeleteOptions{Force: true})
+	assert.Equal(t, len(problems), 0)
+	assert.Equal(t, len(plan.dangling), 2)
+	assert.Equal(t, len(plan.unbind), 0)
+}
+
+func TestParseKind(t *testing.T) {
+	for _, resource := range []string{"apiproxy", "apiproxies", "ApiProxy"} {
+		kind, err := parseKind(resource)
+		assert.Nil(t, err)
+		assert.Equal(t, kind, "ApiProxy")
+	}
+	_, err := parseKind("service")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/northwesternmutual/kanalictl/pkg/cluster"
+	"github.com/northwesternmutual/kanalictl/pkg/diff"
+)
+
+const (
+	// DiffNone is returned by Diff when applying would change nothing.
+	DiffNone = 0
+	// DiffChanges is returned by Diff when applying would change resources.
+	DiffChanges = 1
+	// DiffError is returned by Diff when the diff could not be made.
+	DiffError = 2
+)
+
+// Diff prints a unified diff between the live state of every resource in
+// the files named by inputs and the state applying its configuration would
+// leave it in. Fields populated by the server are ignored and the data of
+// ApiKeys is only reported as changed or unchanged. It returns DiffNone,
+// DiffChanges or DiffError.
+func Diff(inputs []string, recursive bool) int {
+
+	// check if file was passed in
+	if len(inputs) < 1 {
+		fmt.Println("file must be specified")
+		return DiffError
+	}
+
+	docs, err := readDocuments(inputs, recursive)
+	if err != nil {
+		fmt.Println(err.Error())
+		return DiffError
+	}
+
+	problems := []string{}
+	for _, doc := range docs {
+		if err := doc.parse(); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", doc, err.Error()))
+		}
+	}
+	if len(problems) > 0 {
+		fmt.Printf("%d of %d documents are invalid:\n%s\n", len(problems), len(docs), strings.Join(problems, "\n"))
+		return DiffError
+	}
+
+	b := newBatch()
+	client, err := b.client()
+	if err != nil {
+		fmt.Println(err.Error())
+		return DiffError
+	}
+
+	code := DiffNone
+	for _, doc := range docs {
+		text, err := diffDocument(client, doc)
+		if err != nil {
+			fmt.Printf("could not diff %s in namespace %s: %s\n", doc.obj, doc.obj.Namespace, err.Error())
+			return DiffError
+		}
+		if len(text) > 0 {
+			fmt.Print(text)
+			code = DiffChanges
+		}
+	}
+
+	return code
+
+}
+
+// diffDocument returns the diff between the live state of the resource of
+// a document and the state applying the document would leave it in. Fields
+// that only the live state has, such as labels added by others, are kept by
+// apply and so are not shown as removed.
+func diffDocument(client *cluster.Client, doc *document) (string, error) {
+	live, local, err := client.Preview(doc.obj)
+	if err != nil {
+		return "", err
+	}
+
+	live, local, err = diff.Prepare(live, local)
+	if err != nil {
+		return "", err
+	}
+
+	a, err := diff.Lines(live)
+	if err != nil {
+		return "", err
+	}
+	b, err := diff.Lines(local)
+	if err != nil {
+		return "", err
+	}
+
+	name := fmt.Sprintf("%s/%s/%s", doc.obj.Namespace, cluster.Resource(doc.obj.Kind), doc.obj.Name)
+	return diff.Unified("live/"+name, doc.String(), a, b), nil
+}
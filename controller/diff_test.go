@@ -0,0 +1,52 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/northwesternmutual/kanalictl/pkg/cluster"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffDocument(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the team label was added by someone else and is not part of the
+		// last applied configuration
+		w.Write([]byte(`{"apiVersion":"kanali.io/v1","kind":"ApiProxy","metadata":{"name":"foo","namespace":"default","resourceVersion":"3","labels":{"team":"x"},"annotations":{"kubectl.kubernetes.io/last-applied-configuration":"{\"apiVersion\":\"kanali.io/v1\",\"kind\":\"ApiProxy\",\"metadata\":{\"name\":\"foo\"},\"spec\":{\"path\":\"/foo\",\"target\":\"/bar\"}}"}},"spec":{"path":"/foo","target":"/bar"}}`))
+	}))
+	defer ts.Close()
+	client := cluster.NewClient(http.DefaultClient, ts.URL)
+
+	doc := &document{source: "a.yaml", index: 0, data: []byte("apiVersion: kanali.io/v1\nkind: ApiProxy\nmetadata:\n  name: foo\nspec:\n  path: /foo\n  target: /bar\n")}
+	assert.Nil(t, doc.parse())
+	text, err := diffDocument(client, doc)
+	assert.Nil(t, err)
+	assert.Equal(t, text, "")
+
+	doc = &document{source: "a.yaml", index: 0, data: []byte("apiVersion: kanali.io/v1\nkind: ApiProxy\nmetadata:\n  name: foo\nspec:\n  path: /foo\n")}
+	assert.Nil(t, doc.parse())
+	text, err = diffDocument(client, doc)
+	assert.Nil(t, err)
+	assert.Equal(t, text, "--- live/default/apiproxies/foo\n+++ a.yaml#0\n@@ -7,4 +7,3 @@\n   namespace: default\n spec:\n   path: /foo\n-  target: /bar\n")
+}
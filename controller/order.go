@@ -0,0 +1,171 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/northwesternmutual/kanali/spec"
+	"github.com/northwesternmutual/kanalictl/pkg/cluster"
+)
+
+// order returns validated documents in the order they must be submitted,
+// so that the ApiProxy and ApiKeys an ApiKeyBinding references are
+// submitted before it. Documents that need not wait for each other keep
+// the order they were read in. References to resources that are neither
+// in the batch nor in the cluster, and dependency cycles, are returned as
+// problems.
+func (b *batch) order(docs []*document) ([]*document, []string) {
+	deps, problems := b.dependencies(docs)
+	if len(problems) > 0 {
+		return nil, problems
+	}
+
+	ordered := make([]*document, 0, len(docs))
+	done := make([]bool, len(docs))
+	for len(ordered) < len(docs) {
+		next := -1
+		for i := range docs {
+			if !done[i] && ready(deps[i], done) {
+				next = i
+				break
+			}
+		}
+
+		if next < 0 {
+			cycle := []string{}
+			for i, doc := range docs {
+				if !done[i] {
+					cycle = append(cycle, doc.String())
+				}
+			}
+			return nil, []string{fmt.Sprintf("dependency cycle between %s", strings.Join(cycle, ", "))}
+		}
+
+		done[next] = true
+		ordered = append(ordered, docs[next])
+	}
+
+	return ordered, nil
+}
+
+// dependencies returns, for each document, the indexes of the documents
+// its resource references. An ApiKeyBinding references the ApiProxy of the
+// same name in its namespace and, since API keys are referenced by name
+// only, every ApiKey with the name of one of its keys. A reference to a
+// resource that is not in the batch must be to one in the cluster.
+func (b *batch) dependencies(docs []*document) ([][]int, []string) {
+	defined := map[string]int{}
+	keys := map[string][]int{}
+	for i, doc := range docs {
+		defined[objectID(doc.obj.Kind, doc.obj.Namespace, doc.obj.Name)] = i
+		if doc.obj.Kind == "ApiKey" {
+			keys[doc.obj.Name] = append(keys[doc.obj.Name], i)
+		}
+	}
+
+	var clusterKeys map[string]bool
+	problems := []string{}
+	deps := make([][]int, len(docs))
+	for i, doc := range docs {
+		if doc.obj.Kind != "ApiKeyBinding" {
+			continue
+		}
+
+		var binding spec.APIKeyBinding
+		if err := yaml.Unmarshal(doc.data, &binding); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", doc, err.Error()))
+			continue
+		}
+
+		if proxy := binding.Spec.APIProxyName; len(proxy) > 0 {
+			if j, ok := defined[objectID("ApiProxy", doc.obj.Namespace, proxy)]; ok {
+				deps[i] = append(deps[i], j)
+			} else if err := b.inCluster("ApiProxy", doc.obj.Namespace, proxy); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %s", doc, err.Error()))
+			}
+		}
+
+		for _, key := range binding.Spec.Keys {
+			if js, ok := keys[key.Name]; ok {
+				deps[i] = append(deps[i], js...)
+				continue
+			}
+			if clusterKeys == nil {
+				names, err := b.clusterNames("ApiKey")
+				if err != nil {
+					return nil, []string{err.Error()}
+				}
+				clusterKeys = map[string]bool{}
+				for _, name := range names {
+					clusterKeys[name] = true
+				}
+			}
+			if !clusterKeys[key.Name] {
+				problems = append(problems, fmt.Sprintf("%s: ApiKey %q is neither in this batch nor in the cluster", doc, key.Name))
+			}
+		}
+	}
+
+	return deps, problems
+}
+
+// inCluster returns an error unless a resource exists in the cluster.
+func (b *batch) inCluster(kind, namespace, name string) error {
+	client, err := b.client()
+	if err != nil {
+		return err
+	}
+	current, err := client.Get(&cluster.Object{Kind: kind, Name: name, Namespace: namespace})
+	if err != nil {
+		return fmt.Errorf("could not retrieve %s %q in namespace %s: %s", kind, name, namespace, err.Error())
+	}
+	if current == nil {
+		return fmt.Errorf("%s %q in namespace %s is neither in this batch nor in the cluster", kind, name, namespace)
+	}
+	return nil
+}
+
+// clusterNames returns the names of the resources of a kind in every
+// namespace of the cluster.
+func (b *batch) clusterNames(kind string) ([]string, error) {
+	client, err := b.client()
+	if err != nil {
+		return nil, err
+	}
+	names, err := client.Names(kind, "")
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve %s resources: %s", kind, err.Error())
+	}
+	return names, nil
+}
+
+// ready reports whether every document in deps is done.
+func ready(deps []int, done []bool) bool {
+	for _, j := range deps {
+		if !done[j] {
+			return false
+		}
+	}
+	return true
+}
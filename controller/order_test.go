@@ -0,0 +1,66 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/northwesternmutual/kanalictl/pkg/cluster"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrder(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/apis/kanali.io/v1/apikeys":
+			w.Write([]byte(`{"items":[{"metadata":{"name":"c","namespace":"other"}}]}`))
+		case "/apis/kanali.io/v1/namespaces/default/apiproxies/live":
+			w.Write([]byte(`{"kind":"ApiProxy","metadata":{"name":"live"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	parse := func(index int, data string) *document {
+		doc := &document{source: "a.yaml", index: index, data: []byte(data)}
+		assert.Nil(t, doc.parse())
+		return doc
+	}
+	binding := parse(0, "kind: ApiKeyBinding\nmetadata:\n  name: foo\nspec:\n  proxy: foo\n  keys:\n  - name: a\n  - name: c\n")
+	other := parse(1, "kind: ApiKeyBinding\nmetadata:\n  name: bar\nspec:\n  proxy: live\n  keys:\n  - name: c\n")
+	key := parse(2, "kind: ApiKey\nmetadata:\n  name: a\nspec:\n  data: ab\n")
+	proxy := parse(3, "kind: ApiProxy\nmetadata:\n  name: foo\nspec:\n  path: /foo\n")
+
+	b := &batch{api: cluster.NewClient(http.DefaultClient, ts.URL)}
+	ordered, problems := b.order([]*document{binding, other, key, proxy})
+	assert.Equal(t, len(problems), 0)
+	assert.Equal(t, ordered, []*document{other, key, proxy, binding})
+
+	missing := parse(4, "kind: ApiKeyBinding\nmetadata:\n  name: baz\nspec:\n  proxy: baz\n  keys:\n  - name: d\n")
+	_, problems = b.order([]*document{missing})
+	assert.Equal(t, problems, []string{
+		`a.yaml#4: ApiProxy "baz" in namespace default is neither in this batch nor in the cluster`,
+		`a.yaml#4: ApiKey "d" is neither in this batch nor in the cluster`,
+	})
+}
@@ -0,0 +1,169 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/northwesternmutual/kanalictl/pkg/cluster"
+)
+
+// prunedKinds are the only kinds of resources that are pruned.
+var prunedKinds = []string{"ApiKeyBinding", "ApiProxy", "ApiKey"}
+
+// prune deletes the resources of every kind in prunedKinds that have the
+// given labels but are not among the applied documents. Only the namespaces
+// returned by pruneNamespaces are pruned. It makes the same checks as delete
+// without --cascade or --force, so nothing is pruned while a resource that
+// is kept references it.
+func (b *batch) prune(docs []*document, labels map[string]string, namespace string, dryRun bool) int {
+	client, err := b.client()
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	objs, err := pruneCandidates(client, docs, pruneNamespaces(docs, namespace), formatSelector(labels))
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	if len(objs) < 1 {
+		return 0
+	}
+
+	return deleteObjects(objs, DeleteOptions{DryRun: dryRun}, "resources that are still referenced are not pruned")
+}
+
+// pruneCandidates returns the resources of every kind in prunedKinds in the
+// given namespaces that match selector but are not among the applied
+// documents.
+func pruneCandidates(client *cluster.Client, docs []*document, namespaces []string, selector string) ([]*cluster.Object, error) {
+	applied := map[string]bool{}
+	for _, doc := range docs {
+		applied[objectID(doc.obj.Kind, doc.obj.Namespace, doc.obj.Name)] = true
+	}
+
+	objs := []*cluster.Object{}
+	for _, kind := range prunedKinds {
+		for _, namespace := range namespaces {
+			listed, err := client.List(kind, namespace, selector)
+			if err != nil {
+				return nil, fmt.Errorf("could not retrieve %s resources in namespace %s to prune: %s", kind, namespace, err.Error())
+			}
+			for _, obj := range listed {
+				if !applied[objectID(obj.Kind, obj.Namespace, obj.Name)] {
+					objs = append(objs, obj)
+				}
+			}
+		}
+	}
+	return objs, nil
+}
+
+// pruneNamespaces returns the namespaces that are pruned, which are the
+// given namespace or, if it is empty, the namespaces of the applied
+// documents.
+func pruneNamespaces(docs []*document, namespace string) []string {
+	if len(namespace) > 0 {
+		return []string{namespace}
+	}
+
+	seen := map[string]bool{}
+	namespaces := []string{}
+	for _, doc := range docs {
+		if !seen[doc.obj.Namespace] {
+			seen[doc.obj.Namespace] = true
+			namespaces = append(namespaces, doc.obj.Namespace)
+		}
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+// pruneLabels returns the labels that applied resources are given so that
+// they can be pruned, or nil if they are not pruned.
+func pruneLabels(op string, opts Options) (map[string]string, error) {
+	if !opts.Prune {
+		if opts.PruneDryRun {
+			return nil, errors.New("--prune-dry-run can only be used with --prune")
+		}
+		if len(opts.PruneNamespace) > 0 {
+			return nil, errors.New("--namespace can only be used with --prune")
+		}
+		return nil, nil
+	}
+	if op != "apply" {
+		return nil, errors.New("--prune can only be used with apply")
+	}
+	if len(opts.Selector) < 1 {
+		return nil, errors.New("--prune requires a label selector given with -l")
+	}
+	return parseSelector(opts.Selector)
+}
+
+// parseSelector parses a label selector of the form key=value[,key=value].
+// Only selectors that resources can be labeled with are accepted.
+func parseSelector(selector string) (map[string]string, error) {
+	labels := map[string]string{}
+	for _, requirement := range strings.Split(selector, ",") {
+		parts := strings.SplitN(requirement, "=", 2)
+		key := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || len(key) < 1 || strings.HasSuffix(key, "!") || strings.Contains(parts[1], "=") {
+			return nil, fmt.Errorf("invalid label selector %q - must be of the form key=value[,key=value]", selector)
+		}
+		labels[key] = strings.TrimSpace(parts[1])
+	}
+	return labels, nil
+}
+
+// formatSelector returns the label selector matching labels.
+func formatSelector(labels map[string]string) string {
+	requirements := make([]string, 0, len(labels))
+	for key, value := range labels {
+		requirements = append(requirements, key+"="+value)
+	}
+	sort.Strings(requirements)
+	return strings.Join(requirements, ",")
+}
+
+// label adds labels to the resource of every document.
+func label(docs []*document, labels map[string]string) error {
+	if len(labels) < 1 {
+		return nil
+	}
+	for _, doc := range docs {
+		doc.obj.AddLabels(labels)
+		fields, err := doc.obj.Fields()
+		if err != nil {
+			return err
+		}
+		if doc.data, err = yaml.Marshal(fields); err != nil {
+			return err
+		}
+	}
+	return nil
+}
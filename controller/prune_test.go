@@ -0,0 +1,108 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/northwesternmutual/kanalictl/pkg/cluster"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSelector(t *testing.T) {
+	labels, err := parseSelector("app.kubernetes.io/managed-by=kanalictl, team=x")
+	assert.Nil(t, err)
+	assert.Equal(t, labels, map[string]string{"app.kubernetes.io/managed-by": "kanalictl", "team": "x"})
+	assert.Equal(t, formatSelector(labels), "app.kubernetes.io/managed-by=kanalictl,team=x")
+
+	for _, selector := range []string{"team", "team!=x", "team==x", "=x"} {
+		_, err := parseSelector(selector)
+		assert.NotNil(t, err, selector)
+	}
+
+	_, err = pruneLabels("apply", Options{Prune: true})
+	assert.Equal(t, err.Error(), "--prune requires a label selector given with -l")
+	_, err = pruneLabels("apply", Options{PruneNamespace: "a"})
+	assert.Equal(t, err.Error(), "--namespace can only be used with --prune")
+	_, err = pruneLabels("create", Options{Prune: true, Selector: "team=x"})
+	assert.Equal(t, err.Error(), "--prune can only be used with apply")
+	labels, err = pruneLabels("apply", Options{})
+	assert.Nil(t, err)
+	assert.Nil(t, labels)
+}
+
+func TestLabel(t *testing.T) {
+	doc := &document{source: "a.yaml", data: []byte("kind: ApiProxy\nmetadata:\n  name: foo\n  labels:\n    team: x\nspec:\n  path: /foo\n")}
+	assert.Nil(t, doc.parse())
+
+	assert.Nil(t, label([]*document{doc}, map[string]string{"managed-by": "kanalictl"}))
+	assert.Equal(t, string(doc.data), "kind: ApiProxy\nmetadata:\n  labels:\n    managed-by: kanalictl\n    team: x\n  name: foo\n  namespace: default\nspec:\n  path: /foo\n")
+}
+
+func TestPruneCandidates(t *testing.T) {
+	// every listed ApiProxy is labelled, and an ApiProxy in namespace other
+	// is listed when every namespace is
+	proxies := map[string][]string{"a": {"foo", "bar"}, "other": {"baz"}}
+	requests := []string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path)
+		items := []map[string]interface{}{}
+		for namespace, names := range proxies {
+			if !strings.HasSuffix(r.URL.Path, "/apiproxies") || strings.Contains(r.URL.Path, "/namespaces/") && !strings.Contains(r.URL.Path, "/namespaces/"+namespace+"/") {
+				continue
+			}
+			for _, name := range names {
+				items = append(items, map[string]interface{}{"kind": "ApiProxy", "metadata": map[string]interface{}{"name": name, "namespace": namespace, "labels": map[string]interface{}{"team": "x"}}})
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+	}))
+	defer ts.Close()
+	client := cluster.NewClient(http.DefaultClient, ts.URL)
+
+	doc := &document{source: "a.yaml", data: []byte("kind: ApiProxy\nmetadata:\n  name: foo\n  namespace: a\nspec:\n  path: /foo\n")}
+	assert.Nil(t, doc.parse())
+	docs := []*document{doc}
+
+	namespaces := pruneNamespaces(docs, "")
+	assert.Equal(t, namespaces, []string{"a"})
+
+	objs, err := pruneCandidates(client, docs, namespaces, "team=x")
+	assert.Nil(t, err)
+	assert.Equal(t, len(objs), 1)
+	assert.Equal(t, objs[0].Name, "bar")
+	assert.Equal(t, objs[0].Namespace, "a")
+	assert.Equal(t, requests, []string{
+		"/apis/kanali.io/v1/namespaces/a/apikeybindings",
+		"/apis/kanali.io/v1/namespaces/a/apiproxies",
+		"/apis/kanali.io/v1/namespaces/a/apikeys",
+	})
+
+	objs, err = pruneCandidates(client, docs, pruneNamespaces(docs, "other"), "team=x")
+	assert.Nil(t, err)
+	assert.Equal(t, len(objs), 1)
+	assert.Equal(t, objs[0].Name, "baz")
+	assert.Equal(t, objs[0].Namespace, "other")
+}
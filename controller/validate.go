@@ -0,0 +1,102 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/northwesternmutual/kanali/spec"
+	"github.com/northwesternmutual/kanalictl/validation"
+)
+
+// Validate validates the documents in the files named by inputs without
+// changing anything. Paths and proxy names must be unique among the
+// documents and, if againstCluster is set, among the resources in the
+// cluster too, whose ApiProxies and ApiKeys the ApiKeyBindings must then
+// reference unless they are among the documents. Otherwise no cluster is
+// needed.
+func Validate(inputs []string, recursive, againstCluster bool) int {
+
+	// check if file was passed in
+	if len(inputs) < 1 {
+		fmt.Println("file must be specified")
+		return 1
+	}
+
+	docs, err := readDocuments(inputs, recursive)
+	if err != nil {
+		fmt.Println(err.Error())
+		return 1
+	}
+
+	b := newBatch()
+	if !againstCluster {
+		b.resources = localResources(docs)
+	}
+
+	problems := []string{}
+	for _, doc := range docs {
+		if err := b.validate(doc); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", doc, err.Error()))
+		}
+	}
+	if len(problems) < 1 && againstCluster {
+		_, problems = b.order(docs)
+	}
+	if len(problems) > 0 {
+		fmt.Printf("%d of %d documents are invalid:\n%s\n", len(problems), len(docs), strings.Join(problems, "\n"))
+		return 1
+	}
+
+	fmt.Printf("%d documents are valid\n", len(docs))
+	return 0
+
+}
+
+// localResources returns the ApiProxies and ApiKeyBindings among
+// documents. Documents that cannot be read are left out, and reported when
+// they are validated.
+func localResources(docs []*document) validation.Resources {
+	proxies := []spec.APIProxy{}
+	bindings := []spec.APIKeyBinding{}
+	for _, doc := range docs {
+		if err := doc.parse(); err != nil {
+			continue
+		}
+		switch doc.obj.Kind {
+		case "ApiProxy":
+			var proxy spec.APIProxy
+			if err := yaml.Unmarshal(doc.data, &proxy); err == nil {
+				proxy.ObjectMeta.Namespace = doc.obj.Namespace
+				proxies = append(proxies, proxy)
+			}
+		case "ApiKeyBinding":
+			var binding spec.APIKeyBinding
+			if err := yaml.Unmarshal(doc.data, &binding); err == nil {
+				binding.ObjectMeta.Namespace = doc.obj.Namespace
+				bindings = append(bindings, binding)
+			}
+		}
+	}
+	return validation.Local(proxies, bindings)
+}
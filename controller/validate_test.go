@@ -0,0 +1,58 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package controller
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateOffline(t *testing.T) {
+	dir, err := ioutil.TempDir("", "controller")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	proxy := "kind: ApiProxy\nmetadata:\n  name: %s\nspec:\n  path: /foo\n  service:\n    name: foo\n    port: 8080\n"
+	path := filepath.Join(dir, "proxies.yaml")
+
+	assert.Nil(t, ioutil.WriteFile(path, []byte(fmt.Sprintf(proxy, "foo")+"---\nkind: ApiKeyBinding\nmetadata:\n  name: foo\nspec:\n  proxy: foo\n  keys:\n  - name: a\n    defaultRule:\n      global: true\n"), 0644))
+	assert.Equal(t, Validate([]string{path}, false, false), 0)
+
+	assert.Nil(t, ioutil.WriteFile(path, []byte(fmt.Sprintf(proxy, "foo")+"---\n"+fmt.Sprintf(proxy, "bar")), 0644))
+	assert.Equal(t, Validate([]string{path}, false, false), 1)
+
+	b := newBatch()
+	b.resources = localResources(mustRead(t, path))
+	docs := mustRead(t, path)
+	assert.Equal(t, b.validate(docs[0]).Error(), "The ApiProxy bar in namespace default has the same path. Paths must be unique")
+	assert.Equal(t, b.validate(docs[1]).Error(), "the ApiProxy at "+path+"#0 has the same path. Paths must be unique")
+}
+
+func mustRead(t *testing.T, path string) []*document {
+	docs, err := readDocuments([]string{path}, false)
+	assert.Nil(t, err)
+	return docs
+}
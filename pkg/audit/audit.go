@@ -0,0 +1,180 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package audit
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/northwesternmutual/kanalictl/pkg/decrypt"
+	"github.com/northwesternmutual/kanalictl/pkg/generate"
+	"github.com/olekukonko/tablewriter"
+)
+
+// Problem describes what is wrong with an API key.
+type Problem string
+
+const (
+	// ProblemDuplicate is reported for API keys whose data is shared by
+	// another API key resource.
+	ProblemDuplicate Problem = "duplicate"
+	// ProblemWeak is reported for API keys whose data is shorter or has
+	// less entropy than the policy requires.
+	ProblemWeak Problem = "weak"
+	// ProblemUndecryptable is reported for API key resources that could not
+	// be read or decrypted with the private key.
+	ProblemUndecryptable Problem = "undecryptable"
+)
+
+// Finding is a single problem found with an API key resource.
+type Finding struct {
+	Problem   Problem
+	Name      string
+	Namespace string
+	Source    string
+	Document  int
+	Detail    string
+}
+
+// Report is the outcome of auditing API key resources.
+type Report struct {
+	Audited  int
+	Findings []Finding
+}
+
+// Audit checks decrypted API keys for data shared by more than one API key
+// resource, data that is weaker than the policy and API key resources that
+// could not be decrypted. Keys must be at least minLength characters long
+// and have an estimated entropy of at least the policy minimum, which is the
+// rule apikey generate applies to existing key data and to every key it
+// generates.
+func Audit(results []decrypt.Result, policy generate.Policy, minLength int) Report {
+	report := Report{Audited: len(results)}
+
+	groups := map[[sha256.Size]byte][]decrypt.Result{}
+	digests := [][sha256.Size]byte{}
+
+	for _, r := range results {
+		if r.Status != decrypt.StatusDecrypted {
+			report.Findings = append(report.Findings, finding(ProblemUndecryptable, r, r.Error))
+			continue
+		}
+
+		if detail := weakness([]byte(r.Key), policy, minLength); len(detail) > 0 {
+			report.Findings = append(report.Findings, finding(ProblemWeak, r, detail))
+		}
+
+		digest := sha256.Sum256([]byte(r.Key))
+		if _, ok := groups[digest]; !ok {
+			digests = append(digests, digest)
+		}
+		groups[digest] = append(groups[digest], r)
+	}
+
+	for _, digest := range digests {
+		group := groups[digest]
+		if len(group) < 2 {
+			continue
+		}
+		for i, r := range group {
+			others := []string{}
+			for j, other := range group {
+				if i != j {
+					others = append(others, describe(other))
+				}
+			}
+			report.Findings = append(report.Findings, finding(ProblemDuplicate, r, "same data as "+strings.Join(others, ", ")))
+		}
+	}
+
+	sort.Stable(bySource(report.Findings))
+	return report
+}
+
+// Count returns the number of findings of the given problem.
+func (r Report) Count(problem Problem) int {
+	count := 0
+	for _, f := range r.Findings {
+		if f.Problem == problem {
+			count++
+		}
+	}
+	return count
+}
+
+// Render writes the findings of a report to w as a table followed by a
+// summary. API key data is never written.
+func Render(w io.Writer, report Report) {
+	if len(report.Findings) > 0 {
+		table := tablewriter.NewWriter(w)
+		table.SetAutoWrapText(false)
+		table.SetHeader([]string{"API Key Name", "Namespace", "Problem", "Detail", "Source"})
+
+		for _, f := range report.Findings {
+			table.Append([]string{f.Name, f.Namespace, string(f.Problem), f.Detail, fmt.Sprintf("%s#%d", f.Source, f.Document)})
+		}
+
+		table.Render()
+	}
+
+	fmt.Fprintf(w, "audited %d API key resources: %d duplicate, %d weak, %d undecryptable\n",
+		report.Audited, report.Count(ProblemDuplicate), report.Count(ProblemWeak), report.Count(ProblemUndecryptable))
+}
+
+func weakness(keyData []byte, policy generate.Policy, minLength int) string {
+	problems := []string{}
+	if len(keyData) < minLength {
+		problems = append(problems, fmt.Sprintf("%d characters - at least %d are required", len(keyData), minLength))
+	}
+	if entropy := policy.EstimateEntropy(keyData); entropy < policy.MinEntropy {
+		problems = append(problems, fmt.Sprintf("estimated entropy of %.1f bits - at least %.1f bits are required", entropy, policy.MinEntropy))
+	}
+	return strings.Join(problems, "; ")
+}
+
+func finding(problem Problem, r decrypt.Result, detail string) Finding {
+	return Finding{
+		Problem:   problem,
+		Name:      r.Name,
+		Namespace: r.Namespace,
+		Source:    r.Source,
+		Document:  r.Document,
+		Detail:    detail,
+	}
+}
+
+func describe(r decrypt.Result) string {
+	return fmt.Sprintf("%s/%s (%s#%d)", r.Namespace, r.Name, r.Source, r.Document)
+}
+
+type bySource []Finding
+
+func (f bySource) Len() int      { return len(f) }
+func (f bySource) Swap(i, j int) { f[i], f[j] = f[j], f[i] }
+func (f bySource) Less(i, j int) bool {
+	if f[i].Source != f[j].Source {
+		return f[i].Source < f[j].Source
+	}
+	return f[i].Document < f[j].Document
+}
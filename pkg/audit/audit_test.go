@@ -0,0 +1,109 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package audit
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/northwesternmutual/kanalictl/pkg/decrypt"
+	"github.com/northwesternmutual/kanalictl/pkg/generate"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAudit(t *testing.T) {
+	strong := "q7Xk2mZ9vR4tLp8wB3nY6hJ1cF5gD0sAeUiOyTrW"
+	results := []decrypt.Result{
+		{Name: "foo", Namespace: "a", Source: "b.yaml", Document: 0, Status: decrypt.StatusDecrypted, Key: strong},
+		{Name: "bar", Namespace: "b", Source: "a.yaml", Document: 1, Status: decrypt.StatusDecrypted, Key: strong},
+		{Name: "baz", Namespace: "a", Source: "a.yaml", Document: 0, Status: decrypt.StatusDecrypted, Key: "password"},
+		{Name: "qux", Namespace: "a", Source: "b.yaml", Document: 1, Status: decrypt.StatusFailed, Error: "crypto/rsa: decryption error"},
+		{Name: "quux", Namespace: "a", Source: "b.yaml", Document: 2, Status: decrypt.StatusDecrypted, Key: "Z" + strong},
+	}
+
+	report := Audit(results, generate.DefaultPolicy(), 16)
+	assert.Equal(t, report.Audited, 5)
+	assert.Equal(t, report.Count(ProblemDuplicate), 2)
+	assert.Equal(t, report.Count(ProblemWeak), 1)
+	assert.Equal(t, report.Count(ProblemUndecryptable), 1)
+
+	assert.Equal(t, report.Findings[0], Finding{
+		Problem:   ProblemWeak,
+		Name:      "baz",
+		Namespace: "a",
+		Source:    "a.yaml",
+		Document:  0,
+		Detail:    "8 characters - at least 16 are required; estimated entropy of 37.6 bits - at least 128.0 bits are required",
+	})
+	assert.Equal(t, report.Findings[1].Name, "bar")
+	assert.Equal(t, report.Findings[1].Detail, "same data as a/foo (b.yaml#0)")
+	assert.Equal(t, report.Findings[2].Name, "foo")
+	assert.Equal(t, report.Findings[2].Detail, "same data as b/bar (a.yaml#1)")
+	assert.Equal(t, report.Findings[3].Problem, ProblemUndecryptable)
+
+	var buf bytes.Buffer
+	Render(&buf, report)
+	assert.False(t, strings.Contains(buf.String(), strong))
+	assert.False(t, strings.Contains(buf.String(), "password"))
+	assert.True(t, strings.HasSuffix(buf.String(), "audited 5 API key resources: 2 duplicate, 1 weak, 1 undecryptable\n"))
+
+	report = Audit(results[4:], generate.DefaultPolicy(), 16)
+	assert.Empty(t, report.Findings)
+	buf.Reset()
+	Render(&buf, report)
+	assert.Equal(t, buf.String(), "audited 1 API key resources: 0 duplicate, 0 weak, 0 undecryptable\n")
+}
+
+func TestAuditPatterned(t *testing.T) {
+	results := []decrypt.Result{
+		{Name: "foo", Namespace: "a", Source: "a.yaml", Document: 0, Status: decrypt.StatusDecrypted, Key: strings.Repeat("a", 40)},
+		{Name: "bar", Namespace: "a", Source: "a.yaml", Document: 1, Status: decrypt.StatusDecrypted, Key: strings.Repeat("abc", 14)},
+	}
+
+	report := Audit(results, generate.DefaultPolicy(), 16)
+	assert.Equal(t, report.Count(ProblemWeak), 2)
+	assert.Equal(t, report.Findings[0].Name, "foo")
+	assert.Equal(t, report.Findings[0].Detail, "estimated entropy of 14.1 bits - at least 128.0 bits are required")
+}
+
+func TestAuditGenerated(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	results := []decrypt.Result{}
+	for i := 0; i < 500; i++ {
+		keyData, _, _, err := generate.Key("foo", "", 22, generate.DefaultPolicy(), &privateKey.PublicKey)
+		assert.Nil(t, err)
+		results = append(results, decrypt.Result{Name: "foo", Namespace: "a", Status: decrypt.StatusDecrypted, Key: string(keyData)})
+
+		b := make([]byte, 16)
+		_, err = rand.Read(b)
+		assert.Nil(t, err)
+		results = append(results, decrypt.Result{Name: "bar", Namespace: "a", Status: decrypt.StatusDecrypted, Key: hex.EncodeToString(b)})
+	}
+
+	report := Audit(results, generate.DefaultPolicy(), 16)
+	assert.Equal(t, report.Count(ProblemWeak), 0)
+}
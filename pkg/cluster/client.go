@@ -0,0 +1,584 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"k8s.io/kubernetes/pkg/api/unversioned"
+)
+
+// LastAppliedAnnotation records the configuration a resource was last
+// applied with. It is the annotation kubectl uses so that resources can be
+// applied by either.
+const LastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// DefaultNamespace is the namespace resources are created in when their
+// configuration does not name one.
+const DefaultNamespace = "default"
+
+// Doer performs HTTP requests.
+type Doer interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+// Client creates and applies kanali.io resources through the Kubernetes API.
+type Client struct {
+	http   Doer
+	host   string
+	dryRun bool
+}
+
+// Object is a single resource configuration.
+type Object struct {
+	Kind      string
+	Name      string
+	Namespace string
+
+	fields map[string]interface{}
+}
+
+// StatusError is an error returned by the Kubernetes API.
+type StatusError struct {
+	Code    int
+	Reason  string
+	Message string
+}
+
+// NewClient returns a client that sends requests to the Kubernetes API
+// server at host.
+func NewClient(client Doer, host string) *Client {
+	return &Client{http: client, host: host}
+}
+
+// DryRun returns a copy of a client whose requests that change resources
+// are only admitted and validated by the API server, which persists
+// nothing. CheckDryRun must succeed before it is used, since older API
+// servers ignore the request to persist nothing.
+func (c *Client) DryRun() *Client {
+	dryRun := *c
+	dryRun.dryRun = true
+	return &dryRun
+}
+
+// CheckDryRun returns an error unless the API server supports dry run
+// requests, which it does from Kubernetes 1.13.
+func (c *Client) CheckDryRun() error {
+	version := struct {
+		Major string `json:"major"`
+		Minor string `json:"minor"`
+	}{}
+	if err := c.do("GET", c.host+"/version", "", nil, &version); err != nil {
+		return fmt.Errorf("could not retrieve the version of the API server to check that it supports dry run: %s", err.Error())
+	}
+
+	// some providers report minor versions such as 13+
+	major, majorErr := strconv.Atoi(strings.TrimSuffix(version.Major, "+"))
+	minor, minorErr := strconv.Atoi(strings.TrimSuffix(version.Minor, "+"))
+	if majorErr != nil || minorErr != nil {
+		return fmt.Errorf("could not tell whether the API server supports dry run from its version %q.%q", version.Major, version.Minor)
+	}
+
+	if major < 1 || major == 1 && minor < 13 {
+		return fmt.Errorf("the API server runs Kubernetes %d.%d, which does not support dry run and would persist the changes - server dry run requires Kubernetes 1.13 or later", major, minor)
+	}
+	return nil
+}
+
+// ParseObject parses a YAML or JSON resource configuration.
+func ParseObject(data []byte) (*Object, error) {
+	jsonData, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(jsonData, &fields); err != nil {
+		return nil, errors.New("file is not a valid Kubernetes configuration file")
+	}
+
+	obj := &Object{fields: fields}
+	obj.Kind, _ = fields["kind"].(string)
+	if metadata, ok := fields["metadata"].(map[string]interface{}); ok {
+		obj.Name, _ = metadata["name"].(string)
+		obj.Namespace, _ = metadata["namespace"].(string)
+	}
+
+	if len(obj.Kind) < 1 {
+		return nil, errors.New("resource configuration must specify a kind")
+	}
+	if len(obj.Name) < 1 {
+		return nil, fmt.Errorf("%s configuration must specify a name", obj.Kind)
+	}
+	if len(obj.Namespace) < 1 {
+		obj.Namespace = DefaultNamespace
+	}
+
+	return obj, nil
+}
+
+// String describes an object the way kubectl does.
+func (o *Object) String() string {
+	return fmt.Sprintf("%s %q", strings.ToLower(o.Kind), o.Name)
+}
+
+// Fields returns a copy of the configuration of an object with its
+// namespace filled in.
+func (o *Object) Fields() (map[string]interface{}, error) {
+	fields, err := copyFields(o.fields)
+	if err != nil {
+		return nil, err
+	}
+	metadata, _ := fields["metadata"].(map[string]interface{})
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+		fields["metadata"] = metadata
+	}
+	metadata["namespace"] = o.Namespace
+	return fields, nil
+}
+
+// AddLabels adds labels to the configuration of an object, replacing the
+// values of labels it already has.
+func (o *Object) AddLabels(labels map[string]string) {
+	if o.fields == nil {
+		o.fields = map[string]interface{}{}
+	}
+	metadata, _ := o.fields["metadata"].(map[string]interface{})
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+		o.fields["metadata"] = metadata
+	}
+	existing, _ := metadata["labels"].(map[string]interface{})
+	if existing == nil {
+		existing = map[string]interface{}{}
+		metadata["labels"] = existing
+	}
+	for key, value := range labels {
+		existing[key] = value
+	}
+}
+
+// Resource returns the name of the API resource of a kind.
+func Resource(kind string) string {
+	resource := strings.ToLower(kind)
+	if strings.HasSuffix(resource, "y") && !strings.HasSuffix(resource, "ey") {
+		return strings.TrimSuffix(resource, "y") + "ies"
+	}
+	return resource + "s"
+}
+
+// Create creates a resource. It fails if the resource already exists.
+func (c *Client) Create(obj *Object) error {
+	return c.do("POST", c.collectionURL(obj), "application/json", obj.fields, nil)
+}
+
+// Apply creates a resource or, if it already exists, updates it to match
+// its configuration. Fields that were removed from the configuration since
+// it was last applied are removed from the resource while fields set by
+// others are left untouched. It returns whether the resource was created,
+// configured or unchanged.
+func (c *Client) Apply(obj *Object) (string, error) {
+	modified, err := withLastApplied(obj.fields)
+	if err != nil {
+		return "", err
+	}
+
+	current, err := c.Get(obj)
+	if err != nil {
+		return "", err
+	}
+	if current == nil {
+		if err := c.do("POST", c.collectionURL(obj), "application/json", modified, nil); err != nil {
+			return "", err
+		}
+		return "created", nil
+	}
+
+	original, err := lastApplied(current)
+	if err != nil {
+		return "", err
+	}
+
+	patch := mergePatch(original, modified, current)
+	if len(patch) < 1 {
+		return "unchanged", nil
+	}
+
+	// Including the resource version makes the patch fail rather than
+	// overwrite changes made since the resource was retrieved.
+	if metadata, ok := current["metadata"].(map[string]interface{}); ok {
+		if _, ok := patch["metadata"]; !ok {
+			patch["metadata"] = map[string]interface{}{}
+		}
+		if patchMetadata, ok := patch["metadata"].(map[string]interface{}); ok {
+			patchMetadata["resourceVersion"] = metadata["resourceVersion"]
+		}
+	}
+
+	if err := c.do("PATCH", c.objectURL(obj), "application/merge-patch+json", patch, nil); err != nil {
+		return "", err
+	}
+	return "configured", nil
+}
+
+// Preview returns the current state of a resource and the state Apply
+// would leave it in. Fields the configuration has never set, such as labels
+// added by others, are kept as Apply keeps them. If the resource does not
+// exist, the current state is nil and the resource would be created from
+// its configuration.
+func (c *Client) Preview(obj *Object) (map[string]interface{}, map[string]interface{}, error) {
+	current, err := c.Get(obj)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if current == nil {
+		fields, err := obj.Fields()
+		return nil, fields, err
+	}
+
+	modified, err := withLastApplied(obj.fields)
+	if err != nil {
+		return nil, nil, err
+	}
+	original, err := lastApplied(current)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	applied, err := copyFields(current)
+	if err != nil {
+		return nil, nil, err
+	}
+	applyMergePatch(applied, mergePatch(original, modified, current))
+	return current, applied, nil
+}
+
+// Get retrieves the current state of a resource. It returns nil if the
+// resource does not exist.
+func (c *Client) Get(obj *Object) (map[string]interface{}, error) {
+	current := map[string]interface{}{}
+	err := c.do("GET", c.objectURL(obj), "", nil, &current)
+	if IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return current, nil
+}
+
+// Names returns the names of the resources of a kind in a namespace, or in
+// every namespace if namespace is empty.
+func (c *Client) Names(kind, namespace string) ([]string, error) {
+	objs, err := c.List(kind, namespace, "")
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(objs))
+	for _, obj := range objs {
+		names = append(names, obj.Name)
+	}
+	return names, nil
+}
+
+// List returns the resources of a kind in a namespace, or in every
+// namespace if namespace is empty, that match a label selector. An empty
+// selector matches every resource.
+func (c *Client) List(kind, namespace, selector string) ([]*Object, error) {
+	list := struct {
+		Items []map[string]interface{} `json:"items"`
+	}{}
+	listURL := resourceURL(c.host, namespace, Resource(kind))
+	if len(selector) > 0 {
+		listURL += "?labelSelector=" + url.QueryEscape(selector)
+	}
+	if err := c.do("GET", listURL, "", nil, &list); err != nil {
+		return nil, err
+	}
+
+	objs := make([]*Object, 0, len(list.Items))
+	for _, item := range list.Items {
+		obj := &Object{Kind: kind, fields: item}
+		if metadata, ok := item["metadata"].(map[string]interface{}); ok {
+			obj.Name, _ = metadata["name"].(string)
+			obj.Namespace, _ = metadata["namespace"].(string)
+		}
+		objs = append(objs, obj)
+	}
+	return objs, nil
+}
+
+// Delete deletes a resource. Deleting a resource that does not exist
+// succeeds.
+func (c *Client) Delete(obj *Object) error {
+	if err := c.do("DELETE", c.objectURL(obj), "", nil, nil); err != nil && !IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// Update replaces a resource with a state previously retrieved from the
+// cluster and then changed. It fails if the resource has been modified
+// since that state was retrieved.
+func (c *Client) Update(obj *Object, state map[string]interface{}) error {
+	return c.do("PUT", c.objectURL(obj), "application/json", state, nil)
+}
+
+// Restore returns a resource to a state previously retrieved with Get,
+// recreating it if it has since been deleted.
+func (c *Client) Restore(obj *Object, state map[string]interface{}) error {
+	restored, err := copyFields(state)
+	if err != nil {
+		return err
+	}
+	metadata, _ := restored["metadata"].(map[string]interface{})
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+		restored["metadata"] = metadata
+	}
+
+	current, err := c.Get(obj)
+	if err != nil {
+		return err
+	}
+
+	if current == nil {
+		for _, field := range []string{"resourceVersion", "uid", "selfLink", "creationTimestamp"} {
+			delete(metadata, field)
+		}
+		return c.do("POST", c.collectionURL(obj), "application/json", restored, nil)
+	}
+
+	// An update must name the version of the resource it replaces.
+	currentMetadata, _ := current["metadata"].(map[string]interface{})
+	metadata["resourceVersion"] = currentMetadata["resourceVersion"]
+	return c.do("PUT", c.objectURL(obj), "application/json", restored, nil)
+}
+
+// IsNotFound reports whether err is a StatusError for a missing resource.
+func IsNotFound(err error) bool {
+	statusErr, ok := err.(*StatusError)
+	return ok && statusErr.Code == http.StatusNotFound
+}
+
+func (e *StatusError) Error() string {
+	if len(e.Reason) > 0 {
+		return fmt.Sprintf("%s (%d %s)", e.Message, e.Code, e.Reason)
+	}
+	return fmt.Sprintf("%s (%d)", e.Message, e.Code)
+}
+
+func (c *Client) collectionURL(obj *Object) string {
+	return resourceURL(c.host, obj.Namespace, Resource(obj.Kind))
+}
+
+func (c *Client) objectURL(obj *Object) string {
+	return c.collectionURL(obj) + "/" + obj.Name
+}
+
+func (c *Client) do(method, url, contentType string, body, v interface{}) error {
+	if c.dryRun && method != "GET" {
+		url += "?dryRun=All"
+	}
+
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if len(contentType) > 0 {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return statusError(resp, data)
+	}
+
+	if v == nil {
+		return nil
+	}
+	return json.Unmarshal(data, v)
+}
+
+// statusError turns an unsuccessful response into an error, using the
+// Status the Kubernetes API returns if there is one.
+func statusError(resp *http.Response, data []byte) error {
+	status := unversioned.Status{}
+	if err := json.Unmarshal(data, &status); err == nil && len(status.Message) > 0 {
+		return &StatusError{Code: resp.StatusCode, Reason: string(status.Reason), Message: status.Message}
+	}
+
+	message := strings.TrimSpace(string(data))
+	if len(message) < 1 {
+		message = resp.Status
+	}
+	return &StatusError{Code: resp.StatusCode, Message: message}
+}
+
+// withLastApplied returns a copy of a configuration annotated with itself.
+func withLastApplied(fields map[string]interface{}) (map[string]interface{}, error) {
+	last, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	modified, err := copyFields(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, ok := modified["metadata"].(map[string]interface{})
+	if !ok {
+		metadata = map[string]interface{}{}
+		modified["metadata"] = metadata
+	}
+	annotations, ok := metadata["annotations"].(map[string]interface{})
+	if !ok {
+		annotations = map[string]interface{}{}
+		metadata["annotations"] = annotations
+	}
+	annotations[LastAppliedAnnotation] = string(last)
+
+	return modified, nil
+}
+
+// copyFields returns a deep copy of a resource.
+func copyFields(fields map[string]interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	copied := map[string]interface{}{}
+	if err := json.Unmarshal(data, &copied); err != nil {
+		return nil, err
+	}
+	return copied, nil
+}
+
+func annotation(fields map[string]interface{}, name string) string {
+	metadata, _ := fields["metadata"].(map[string]interface{})
+	annotations, _ := metadata["annotations"].(map[string]interface{})
+	value, _ := annotations[name].(string)
+	return value
+}
+
+// lastApplied returns the configuration a resource was last applied with,
+// which is empty if it has never been applied.
+func lastApplied(current map[string]interface{}) (map[string]interface{}, error) {
+	original := map[string]interface{}{}
+	if last := annotation(current, LastAppliedAnnotation); len(last) > 0 {
+		if err := json.Unmarshal([]byte(last), &original); err != nil {
+			return nil, fmt.Errorf("could not parse the last applied configuration: %s", err.Error())
+		}
+	}
+	return original, nil
+}
+
+// applyMergePatch changes a resource the way the API server applies a JSON
+// merge patch to it.
+func applyMergePatch(obj, patch map[string]interface{}) {
+	for key, value := range patch {
+		if value == nil {
+			delete(obj, key)
+			continue
+		}
+		valueMap, isMap := value.(map[string]interface{})
+		if !isMap {
+			obj[key] = value
+			continue
+		}
+		objMap, objIsMap := obj[key].(map[string]interface{})
+		if !objIsMap {
+			objMap = map[string]interface{}{}
+			obj[key] = objMap
+		}
+		applyMergePatch(objMap, valueMap)
+	}
+}
+
+// mergePatch returns a JSON merge patch that changes current to match
+// modified. Fields that were in the original configuration but are no
+// longer in modified are removed. Lists are replaced as a whole.
+func mergePatch(original, modified, current map[string]interface{}) map[string]interface{} {
+	patch := map[string]interface{}{}
+
+	for key, value := range modified {
+		currentValue, exists := current[key]
+		valueMap, isMap := value.(map[string]interface{})
+		currentMap, currentIsMap := currentValue.(map[string]interface{})
+		if isMap && currentIsMap {
+			originalMap, _ := original[key].(map[string]interface{})
+			if sub := mergePatch(originalMap, valueMap, currentMap); len(sub) > 0 {
+				patch[key] = sub
+			}
+			continue
+		}
+		if !exists || !reflect.DeepEqual(value, currentValue) {
+			patch[key] = value
+		}
+	}
+
+	for key := range original {
+		if _, ok := modified[key]; ok {
+			continue
+		}
+		if _, ok := current[key]; ok {
+			patch[key] = nil
+		}
+	}
+
+	return patch
+}
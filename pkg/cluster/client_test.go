@@ -0,0 +1,344 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeServer is an in memory Kubernetes API that supports the requests
+// made by Client.
+type fakeServer struct {
+	objects  map[string]map[string]interface{}
+	requests []string
+	patches  []map[string]interface{}
+}
+
+func (s *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.requests = append(s.requests, r.Method+" "+r.URL.RequestURI())
+	body, _ := ioutil.ReadAll(r.Body)
+	if r.URL.Query().Get("dryRun") == "All" {
+		// a dry run is handled as usual and then forgotten
+		data, _ := json.Marshal(s.objects)
+		defer func() {
+			s.objects = map[string]map[string]interface{}{}
+			json.Unmarshal(data, &s.objects)
+		}()
+	}
+	fields := map[string]interface{}{}
+	if len(body) > 0 {
+		json.Unmarshal(body, &fields)
+	}
+
+	switch r.Method {
+	case "GET":
+		obj, ok := s.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"kind":"Status","status":"Failure","message":"not found","reason":"NotFound","code":404}`))
+			return
+		}
+		json.NewEncoder(w).Encode(obj)
+	case "POST":
+		name := fields["metadata"].(map[string]interface{})["name"].(string)
+		path := r.URL.Path + "/" + name
+		if _, ok := s.objects[path]; ok {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"kind":"Status","status":"Failure","message":"apikeys.kanali.io \"` + name + `\" already exists","reason":"AlreadyExists","code":409}`))
+			return
+		}
+		fields["metadata"].(map[string]interface{})["resourceVersion"] = "1"
+		s.objects[path] = fields
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(fields)
+	case "PUT":
+		current, ok := s.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if fields["metadata"].(map[string]interface{})["resourceVersion"] != current["metadata"].(map[string]interface{})["resourceVersion"] {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"kind":"Status","status":"Failure","message":"the object has been modified","reason":"Conflict","code":409}`))
+			return
+		}
+		s.objects[r.URL.Path] = fields
+		json.NewEncoder(w).Encode(fields)
+	case "DELETE":
+		if _, ok := s.objects[r.URL.Path]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"kind":"Status","status":"Failure","message":"not found","reason":"NotFound","code":404}`))
+			return
+		}
+		delete(s.objects, r.URL.Path)
+		w.Write([]byte(`{"kind":"Status","status":"Success"}`))
+	case "PATCH":
+		if r.Header.Get("Content-Type") != "application/merge-patch+json" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		s.patches = append(s.patches, fields)
+		applyMergePatch(s.objects[r.URL.Path], fields)
+		json.NewEncoder(w).Encode(s.objects[r.URL.Path])
+	}
+}
+
+func TestParseObject(t *testing.T) {
+	obj, err := ParseObject([]byte("apiVersion: kanali.io/v1\nkind: ApiProxy\nmetadata:\n  name: foo\nspec:\n  path: /foo\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, obj.Kind, "ApiProxy")
+	assert.Equal(t, obj.Name, "foo")
+	assert.Equal(t, obj.Namespace, DefaultNamespace)
+	assert.Equal(t, obj.String(), `apiproxy "foo"`)
+
+	_, err = ParseObject([]byte("kind: ApiProxy\nmetadata: {}\n"))
+	assert.Equal(t, err.Error(), "ApiProxy configuration must specify a name")
+
+	assert.Equal(t, Resource("ApiKey"), "apikeys")
+	assert.Equal(t, Resource("ApiProxy"), "apiproxies")
+	assert.Equal(t, Resource("ApiKeyBinding"), "apikeybindings")
+}
+
+func TestCreate(t *testing.T) {
+	server := &fakeServer{objects: map[string]map[string]interface{}{}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	client := NewClient(http.DefaultClient, ts.URL)
+
+	obj, err := ParseObject([]byte("apiVersion: kanali.io/v1\nkind: ApiKey\nmetadata:\n  name: foo\n  namespace: a\nspec:\n  data: ab\n"))
+	assert.Nil(t, err)
+
+	assert.Nil(t, client.Create(obj))
+	assert.Equal(t, server.requests, []string{"POST /apis/kanali.io/v1/namespaces/a/apikeys"})
+	assert.Equal(t, server.objects["/apis/kanali.io/v1/namespaces/a/apikeys/foo"]["spec"], map[string]interface{}{"data": "ab"})
+
+	err = client.Create(obj)
+	assert.Equal(t, err, &StatusError{Code: 409, Reason: "AlreadyExists", Message: `apikeys.kanali.io "foo" already exists`})
+	assert.Equal(t, err.Error(), `apikeys.kanali.io "foo" already exists (409 AlreadyExists)`)
+}
+
+func TestApply(t *testing.T) {
+	server := &fakeServer{objects: map[string]map[string]interface{}{}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	client := NewClient(http.DefaultClient, ts.URL)
+	path := "/apis/kanali.io/v1/namespaces/default/apiproxies/foo"
+
+	apply := func(config string) string {
+		obj, err := ParseObject([]byte(config))
+		assert.Nil(t, err)
+		result, err := client.Apply(obj)
+		assert.Nil(t, err)
+		return result
+	}
+
+	config := "apiVersion: kanali.io/v1\nkind: ApiProxy\nmetadata:\n  name: foo\nspec:\n  path: /foo\n  target: /bar\n"
+	assert.Equal(t, apply(config), "created")
+	assert.True(t, strings.Contains(annotation(server.objects[path], LastAppliedAnnotation), `"target":"/bar"`))
+
+	assert.Equal(t, apply(config), "unchanged")
+
+	// a label set by someone else is kept while the removed target is deleted
+	server.objects[path]["metadata"].(map[string]interface{})["labels"] = map[string]interface{}{"team": "x"}
+	assert.Equal(t, apply("apiVersion: kanali.io/v1\nkind: ApiProxy\nmetadata:\n  name: foo\nspec:\n  path: /baz\n"), "configured")
+	assert.Equal(t, server.objects[path]["spec"], map[string]interface{}{"path": "/baz"})
+	assert.Equal(t, server.objects[path]["metadata"].(map[string]interface{})["labels"], map[string]interface{}{"team": "x"})
+	assert.Equal(t, server.patches[0]["metadata"].(map[string]interface{})["resourceVersion"], "1")
+
+	assert.Equal(t, server.requests, []string{
+		"GET " + path,
+		"POST /apis/kanali.io/v1/namespaces/default/apiproxies",
+		"GET " + path,
+		"GET " + path,
+		"PATCH " + path,
+	})
+}
+
+func TestPreview(t *testing.T) {
+	server := &fakeServer{objects: map[string]map[string]interface{}{}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	client := NewClient(http.DefaultClient, ts.URL)
+	path := "/apis/kanali.io/v1/namespaces/default/apiproxies/foo"
+
+	obj, err := ParseObject([]byte("apiVersion: kanali.io/v1\nkind: ApiProxy\nmetadata:\n  name: foo\nspec:\n  path: /foo\n  target: /bar\n"))
+	assert.Nil(t, err)
+
+	current, applied, err := client.Preview(obj)
+	assert.Nil(t, err)
+	assert.Nil(t, current)
+	assert.Equal(t, applied["metadata"], map[string]interface{}{"name": "foo", "namespace": "default"})
+
+	_, err = client.Apply(obj)
+	assert.Nil(t, err)
+	server.objects[path]["metadata"].(map[string]interface{})["labels"] = map[string]interface{}{"team": "x"}
+
+	// the label set by someone else is kept while the removed target is
+	// deleted
+	obj, err = ParseObject([]byte("apiVersion: kanali.io/v1\nkind: ApiProxy\nmetadata:\n  name: foo\nspec:\n  path: /baz\n"))
+	assert.Nil(t, err)
+	current, applied, err = client.Preview(obj)
+	assert.Nil(t, err)
+	assert.Equal(t, current["spec"], map[string]interface{}{"path": "/foo", "target": "/bar"})
+	assert.Equal(t, applied["spec"], map[string]interface{}{"path": "/baz"})
+	assert.Equal(t, applied["metadata"].(map[string]interface{})["labels"], map[string]interface{}{"team": "x"})
+	assert.Equal(t, server.objects[path]["spec"], map[string]interface{}{"path": "/foo", "target": "/bar"})
+}
+
+func TestDryRun(t *testing.T) {
+	server := &fakeServer{objects: map[string]map[string]interface{}{}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	client := NewClient(http.DefaultClient, ts.URL)
+	path := "/apis/kanali.io/v1/namespaces/default/apiproxies/foo"
+
+	obj, err := ParseObject([]byte("apiVersion: kanali.io/v1\nkind: ApiProxy\nmetadata:\n  name: foo\nspec:\n  path: /foo\n"))
+	assert.Nil(t, err)
+
+	result, err := client.DryRun().Apply(obj)
+	assert.Nil(t, err)
+	assert.Equal(t, result, "created")
+	assert.Nil(t, server.objects[path])
+
+	assert.Nil(t, client.Create(obj))
+	assert.Equal(t, client.DryRun().Create(obj).(*StatusError).Reason, "AlreadyExists")
+	assert.Nil(t, client.DryRun().Delete(obj))
+	assert.NotNil(t, server.objects[path])
+
+	assert.Equal(t, server.requests, []string{
+		"GET " + path,
+		"POST /apis/kanali.io/v1/namespaces/default/apiproxies?dryRun=All",
+		"POST /apis/kanali.io/v1/namespaces/default/apiproxies",
+		"POST /apis/kanali.io/v1/namespaces/default/apiproxies?dryRun=All",
+		"DELETE " + path + "?dryRun=All",
+	})
+}
+
+func TestCheckDryRun(t *testing.T) {
+	server := &fakeServer{objects: map[string]map[string]interface{}{}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	client := NewClient(http.DefaultClient, ts.URL)
+
+	assert.Contains(t, client.CheckDryRun().Error(), "could not retrieve the version of the API server to check that it supports dry run: ")
+
+	for version, supported := range map[[2]string]bool{
+		{"1", "5"}:   false,
+		{"1", "12+"}: false,
+		{"1", "13"}:  true,
+		{"1", "13+"}: true,
+		{"2", "0"}:   true,
+	} {
+		server.objects["/version"] = map[string]interface{}{"major": version[0], "minor": version[1], "gitVersion": "v" + version[0] + "." + version[1]}
+		assert.Equal(t, client.CheckDryRun() == nil, supported, version)
+	}
+
+	server.objects["/version"] = map[string]interface{}{"major": "1", "minor": "5"}
+	assert.Equal(t, client.CheckDryRun().Error(), "the API server runs Kubernetes 1.5, which does not support dry run and would persist the changes - server dry run requires Kubernetes 1.13 or later")
+
+	server.objects["/version"] = map[string]interface{}{"major": "", "minor": ""}
+	assert.Equal(t, client.CheckDryRun().Error(), `could not tell whether the API server supports dry run from its version "".""`)
+}
+
+func TestList(t *testing.T) {
+	requests := []string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RequestURI())
+		w.Write([]byte(`{"items":[{"kind":"ApiProxy","metadata":{"name":"foo","namespace":"a","labels":{"team":"x"}}}]}`))
+	}))
+	defer ts.Close()
+	client := NewClient(http.DefaultClient, ts.URL)
+
+	objs, err := client.List("ApiProxy", "", "team=x")
+	assert.Nil(t, err)
+	assert.Equal(t, len(objs), 1)
+	assert.Equal(t, objs[0].Name, "foo")
+	assert.Equal(t, objs[0].Namespace, "a")
+
+	names, err := client.Names("ApiProxy", "a")
+	assert.Nil(t, err)
+	assert.Equal(t, names, []string{"foo"})
+
+	assert.Equal(t, requests, []string{
+		"/apis/kanali.io/v1/apiproxies?labelSelector=team%3Dx",
+		"/apis/kanali.io/v1/namespaces/a/apiproxies",
+	})
+
+	obj := &Object{Kind: "ApiProxy", Name: "foo", Namespace: "a"}
+	obj.AddLabels(map[string]string{"team": "y"})
+	fields, err := obj.Fields()
+	assert.Nil(t, err)
+	assert.Equal(t, fields["metadata"], map[string]interface{}{"namespace": "a", "labels": map[string]interface{}{"team": "y"}})
+}
+
+func TestRestore(t *testing.T) {
+	server := &fakeServer{objects: map[string]map[string]interface{}{}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	client := NewClient(http.DefaultClient, ts.URL)
+	path := "/apis/kanali.io/v1/namespaces/default/apiproxies/foo"
+
+	obj, err := ParseObject([]byte("apiVersion: kanali.io/v1\nkind: ApiProxy\nmetadata:\n  name: foo\nspec:\n  path: /foo\n"))
+	assert.Nil(t, err)
+
+	prior, err := client.Get(obj)
+	assert.Nil(t, err)
+	assert.Nil(t, prior)
+
+	assert.Nil(t, client.Create(obj))
+	prior, err = client.Get(obj)
+	assert.Nil(t, err)
+	assert.Equal(t, prior["spec"], map[string]interface{}{"path": "/foo"})
+
+	server.objects[path]["spec"] = map[string]interface{}{"path": "/bar"}
+	server.objects[path]["metadata"].(map[string]interface{})["resourceVersion"] = "2"
+	assert.Nil(t, client.Restore(obj, prior))
+	assert.Equal(t, server.objects[path]["spec"], map[string]interface{}{"path": "/foo"})
+	assert.Equal(t, prior["metadata"].(map[string]interface{})["resourceVersion"], "1")
+
+	assert.Nil(t, client.Delete(obj))
+	assert.Nil(t, client.Delete(obj))
+	assert.Nil(t, client.Restore(obj, prior))
+	assert.Equal(t, server.objects[path]["spec"], map[string]interface{}{"path": "/foo"})
+
+	assert.True(t, IsNotFound(&StatusError{Code: 404}))
+	assert.False(t, IsNotFound(&StatusError{Code: 409}))
+}
+
+func TestMergePatch(t *testing.T) {
+	original := map[string]interface{}{"a": "1", "b": "2", "m": map[string]interface{}{"x": "1", "y": "2"}}
+	modified := map[string]interface{}{"a": "1", "c": "3", "m": map[string]interface{}{"x": "2"}}
+	current := map[string]interface{}{"a": "1", "b": "2", "d": "4", "m": map[string]interface{}{"x": "1", "y": "2", "z": "3"}}
+
+	assert.Equal(t, mergePatch(original, modified, current), map[string]interface{}{
+		"b": nil,
+		"c": "3",
+		"m": map[string]interface{}{"x": "2", "y": nil},
+	})
+	assert.Empty(t, mergePatch(modified, modified, modified))
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/northwesternmutual/kanali/spec"
+	"github.com/northwesternmutual/kanalictl/utils"
+	"github.com/northwesternmutual/kanalictl/validation"
+)
+
+// APIKeys retrieves the ApiKeys in a namespace, or in every namespace if
+// namespace is empty.
+func APIKeys(client utils.HTTPClient, host, namespace string) ([]spec.APIKey, error) {
+	list := &spec.APIKeyList{}
+	if err := get(client, resourceURL(host, namespace, "apikeys"), list); err != nil {
+		return nil, err
+	}
+	return list.Keys, nil
+}
+
+// APIKeyBindings retrieves the ApiKeyBindings in a namespace, or in every
+// namespace if namespace is empty.
+func APIKeyBindings(client utils.HTTPClient, host, namespace string) ([]spec.APIKeyBinding, error) {
+	list := &spec.APIKeyBindingList{}
+	if err := get(client, resourceURL(host, namespace, "apikeybindings"), list); err != nil {
+		return nil, err
+	}
+	return list.Bindings, nil
+}
+
+// APIProxies retrieves the ApiProxies in a namespace, or in every namespace
+// if namespace is empty.
+func APIProxies(client utils.HTTPClient, host, namespace string) ([]spec.APIProxy, error) {
+	list := &spec.APIProxyList{}
+	if err := get(client, resourceURL(host, namespace, "apiproxies"), list); err != nil {
+		return nil, err
+	}
+	return list.Proxies, nil
+}
+
+// UpdateBinding replaces an ApiKeyBinding retrieved from the cluster with a
+// changed version of it. The replacement fails if the ApiKeyBinding has been
+// modified since it was retrieved.
+func (c *Client) UpdateBinding(binding spec.APIKeyBinding) error {
+	// items of a list do not always say what they are
+	binding.TypeMeta.Kind = "ApiKeyBinding"
+	binding.TypeMeta.APIVersion = validation.APIName + "/" + validation.APIVersion
+
+	data, err := json.Marshal(binding)
+	if err != nil {
+		return err
+	}
+	state := map[string]interface{}{}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	return c.Update(&Object{Kind: "ApiKeyBinding", Name: binding.ObjectMeta.Name, Namespace: binding.ObjectMeta.Namespace}, state)
+}
+
+func resourceURL(host, namespace, resource string) string {
+	if len(namespace) < 1 {
+		return fmt.Sprintf("%s/apis/%s/%s/%s", host, validation.APIName, validation.APIVersion, resource)
+	}
+	return fmt.Sprintf("%s/apis/%s/%s/namespaces/%s/%s", host, validation.APIName, validation.APIVersion, namespace, resource)
+}
+
+func get(client utils.HTTPClient, url string, v interface{}) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("could not retrieve %s: %s %s", url, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
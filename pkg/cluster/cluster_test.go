@@ -0,0 +1,108 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cluster
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/northwesternmutual/kanali/spec"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/kubernetes/pkg/api"
+)
+
+type fakeClient struct {
+	urls      []string
+	responses map[string]string
+}
+
+func (c *fakeClient) Get(url string) (*http.Response, error) {
+	c.urls = append(c.urls, url)
+	body, ok := c.responses[url]
+	if !ok {
+		return &http.Response{
+			StatusCode: http.StatusNotFound,
+			Status:     "404 Not Found",
+			Body:       ioutil.NopCloser(bytes.NewBufferString("not found")),
+		}, nil
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(bytes.NewBufferString(body)),
+	}, nil
+}
+
+func TestAPIKeys(t *testing.T) {
+	client := &fakeClient{responses: map[string]string{
+		"https://master/apis/kanali.io/v1/apikeys":                     `{"kind":"ApiKeyList","items":[{"kind":"ApiKey","metadata":{"name":"foo","namespace":"a"},"spec":{"data":"ab"}},{"kind":"ApiKey","metadata":{"name":"bar","namespace":"b"},"spec":{"data":"cd"}}]}`,
+		"https://master/apis/kanali.io/v1/namespaces/a/apikeys":        `{"kind":"ApiKeyList","items":[{"kind":"ApiKey","metadata":{"name":"foo","namespace":"a"},"spec":{"data":"ab"}}]}`,
+		"https://master/apis/kanali.io/v1/namespaces/a/apikeybindings": `{"kind":"ApiKeyBindingList","items":[{"kind":"ApiKeyBinding","metadata":{"name":"foo","namespace":"a"},"spec":{"proxy":"foo","keys":[{"name":"foo"}]}}]}`,
+		"https://master/apis/kanali.io/v1/apiproxies":                  `{"kind":"ApiProxyList","items":[{"kind":"ApiProxy","metadata":{"name":"foo","namespace":"a"},"spec":{"path":"/foo"}}]}`,
+	}}
+
+	keys, err := APIKeys(client, "https://master", "")
+	assert.Nil(t, err)
+	assert.Equal(t, len(keys), 2)
+	assert.Equal(t, keys[1].ObjectMeta.Name, "bar")
+
+	keys, err = APIKeys(client, "https://master", "a")
+	assert.Nil(t, err)
+	assert.Equal(t, len(keys), 1)
+
+	bindings, err := APIKeyBindings(client, "https://master", "a")
+	assert.Nil(t, err)
+	assert.Equal(t, bindings[0].Spec.Keys[0].Name, "foo")
+
+	proxies, err := APIProxies(client, "https://master", "")
+	assert.Nil(t, err)
+	assert.Equal(t, proxies[0].Spec.Path, "/foo")
+
+	_, err = APIKeys(client, "https://master", "missing")
+	assert.Equal(t, err.Error(), "could not retrieve https://master/apis/kanali.io/v1/namespaces/missing/apikeys: 404 Not Found not found")
+}
+
+func TestUpdateBinding(t *testing.T) {
+	server := &fakeServer{objects: map[string]map[string]interface{}{}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	client := NewClient(http.DefaultClient, ts.URL)
+	path := "/apis/kanali.io/v1/namespaces/a/apikeybindings/foo"
+
+	obj, err := ParseObject([]byte("apiVersion: kanali.io/v1\nkind: ApiKeyBinding\nmetadata:\n  name: foo\n  namespace: a\nspec:\n  proxy: foo\n  keys:\n  - name: foo\n  - name: bar\n"))
+	assert.Nil(t, err)
+	assert.Nil(t, client.Create(obj))
+
+	// items of a list do not say what they are
+	binding := spec.APIKeyBinding{
+		ObjectMeta: api.ObjectMeta{Name: "foo", Namespace: "a", ResourceVersion: "1"},
+		Spec:       spec.APIKeyBindingSpec{APIProxyName: "foo", Keys: []spec.Key{{Name: "bar"}}},
+	}
+	assert.Nil(t, client.UpdateBinding(binding))
+	assert.Equal(t, server.requests[len(server.requests)-1], "PUT "+path)
+	assert.Equal(t, server.objects[path]["kind"], "ApiKeyBinding")
+	assert.Equal(t, server.objects[path]["apiVersion"], "kanali.io/v1")
+	keys := server.objects[path]["spec"].(map[string]interface{})["keys"].([]interface{})
+	assert.Equal(t, len(keys), 1)
+	assert.Equal(t, keys[0].(map[string]interface{})["name"], "bar")
+}
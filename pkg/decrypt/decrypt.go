@@ -1,24 +1,18 @@
 package decrypt
 
 import (
-	"bufio"
-	"bytes"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
-	"fmt"
-	"io"
-	"io/ioutil"
-	"os"
-	"path/filepath"
+	"sort"
 	"sync"
 
 	"github.com/ghodss/yaml"
 	"github.com/northwesternmutual/kanali/spec"
-	"github.com/olekukonko/tablewriter"
-	yamlReader "k8s.io/apimachinery/pkg/util/yaml"
+	"github.com/northwesternmutual/kanalictl/pkg/cluster"
+	"github.com/northwesternmutual/kanalictl/pkg/manifest"
 	"k8s.io/kubernetes/pkg/api/unversioned"
 )
 
@@ -27,153 +21,235 @@ const (
 	label        = "kanali"
 )
 
-type result struct {
-	name string
-	data string
+// Status describes whether an API key could be decrypted.
+type Status string
+
+const (
+	// StatusDecrypted is the status of API keys that were decrypted.
+	StatusDecrypted Status = "decrypted"
+	// StatusFailed is the status of API keys that could not be decrypted.
+	StatusFailed Status = "failed"
+)
+
+// Result is the outcome of decrypting a single API key resource.
+type Result struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Source    string `json:"source"`
+	Document  int    `json:"document"`
+	Status    Status `json:"status"`
+	Key       string `json:"key,omitempty"`
+	Error     string `json:"error,omitempty"`
 }
 
-// Do attempts to decrypt all API key resources recursively found
-// under the specified file or directory.
-func Do(inFilePath string, key *rsa.PrivateKey) error {
-	fileList, err := discoverFiles(inFilePath)
-	if err != nil {
-		return err
+// Filter selects which API key resources are decrypted. An empty filter
+// selects every API key resource. An API key resource that does not name a
+// namespace is in the default namespace.
+type Filter struct {
+	Names     []string
+	Namespace string
+}
+
+// Matches reports whether an API key resource is selected by the filter.
+func (f Filter) Matches(apikey spec.APIKey) bool {
+	if len(f.Namespace) > 0 && apikey.ObjectMeta.Namespace != f.Namespace {
+		return false
+	}
+	if len(f.Names) < 1 {
+		return true
+	}
+	for _, name := range f.Names {
+		if apikey.ObjectMeta.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Files attempts to decrypt all API key resources recursively found
+// under the specified file or directory that match the filter. At most
+// concurrency API keys are decrypted at once. The results are sorted by
+// file and then by document, and every document that could not be read,
+// parsed or decrypted is reported as a failed result.
+func Files(inFilePath string, key *rsa.PrivateKey, filter Filter, concurrency int) ([]Result, error) {
+	if concurrency < 1 {
+		return nil, errors.New("concurrency must be at least 1")
 	}
 
-	renderResults(decryptFiles(fileList, key))
+	fileList, err := manifest.Discover(inFilePath)
+	if err != nil {
+		return nil, err
+	}
 
-	return nil
+	return decryptAll(func(jobs chan<- job, results chan<- Result) {
+		for _, file := range fileList {
+			readFile(file, filter, jobs, results)
+		}
+	}, key, concurrency), nil
 }
 
-func renderResults(results []result) {
-	if len(results) < 1 {
-		fmt.Println("no API keys found")
+// Keys attempts to decrypt API key resources that have already been
+// retrieved, such as those deployed to a cluster, that match the filter.
+// Every result is attributed to source and ordered as the keys were given.
+func Keys(apikeys []spec.APIKey, source string, key *rsa.PrivateKey, filter Filter, concurrency int) ([]Result, error) {
+	if concurrency < 1 {
+		return nil, errors.New("concurrency must be at least 1")
 	}
 
-	table := tablewriter.NewWriter(os.Stdout)
-	table.SetAutoWrapText(false)
-	table.SetHeader([]string{"API Key Name", "Result"})
+	return decryptAll(func(jobs chan<- job, results chan<- Result) {
+		for index, apikey := range apikeys {
+			if filter.Matches(apikey) {
+				jobs <- job{apikey: apikey, source: source, index: index}
+			}
+		}
+	}, key, concurrency), nil
+}
 
+// Failed returns the number of results that could not be decrypted.
+func Failed(results []Result) int {
+	failed := 0
 	for _, r := range results {
-		table.Append([]string{r.name, r.data})
+		if r.Status == StatusFailed {
+			failed++
+		}
 	}
+	return failed
+}
 
-	table.Render()
+// job is a single API key resource waiting to be decrypted.
+type job struct {
+	apikey spec.APIKey
+	source string
+	index  int
 }
 
-func decryptFiles(fileList []string, key *rsa.PrivateKey) []result {
-	allResults := []result{}
-	resultsChan := make(chan []result)
+// decryptAll hands the API key resources found by produce to a fixed pool
+// of workers. Failures found by produce, such as files that could not be
+// read, are sent straight to the results so that they are reported in place.
+func decryptAll(produce func(chan<- job, chan<- Result), key *rsa.PrivateKey, concurrency int) []Result {
+	jobs := make(chan job)
+	resultsChan := make(chan Result)
 
 	wg := sync.WaitGroup{}
-	wg.Add(len(fileList))
+	wg.Add(concurrency + 1)
 
-	for _, file := range fileList {
-		go func(file string) {
-			resultsChan <- decryptFile(file, key)
-		}(file)
+	go func() {
+		defer wg.Done()
+		defer close(jobs)
+		produce(jobs, resultsChan)
+	}()
+
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				resultsChan <- decryptKey(j.apikey, j.source, j.index, key)
+			}
+		}()
 	}
+
 	go func() {
-		for r := range resultsChan {
-			allResults = append(allResults, r...)
-			wg.Done()
-		}
+		wg.Wait()
+		close(resultsChan)
 	}()
 
-	wg.Wait()
+	allResults := []Result{}
+	for r := range resultsChan {
+		allResults = append(allResults, r)
+	}
+
+	sort.Stable(bySource(allResults))
 	return allResults
 }
 
-func decryptFile(file string, key *rsa.PrivateKey) []result {
-	fileResults := []result{}
-	fileResultsChan := make(chan result)
-	wg := sync.WaitGroup{}
-
-	fileData, err := ioutil.ReadFile(file)
+func readFile(file string, filter Filter, jobs chan<- job, results chan<- Result) {
+	f, err := manifest.Read(file)
 	if err != nil {
-		return fileResults
+		results <- failed(file, 0, err)
+		return
 	}
 
-	reader := yamlReader.NewYAMLReader(bufio.NewReader(bytes.NewReader(fileData)))
-
-	for {
-		doc, err := reader.Read()
-		if err != nil && err != io.EOF {
-			return fileResults
-		} else if err != nil && err == io.EOF {
-			break
+	for _, doc := range f.Documents {
+		if doc.Empty() {
+			continue
 		}
 
-		wg.Add(1)
-		go func(doc []byte) {
-			name, data, err := decryptKey(doc, key)
-			if err != nil {
-				wg.Done()
-			} else {
-				fileResultsChan <- result{
-					name: name,
-					data: data,
-				}
-			}
-		}(doc)
-		go func() {
-			for r := range fileResultsChan {
-				fileResults = append(fileResults, r)
-				wg.Done()
-			}
-		}()
+		apikey, ok, err := parseKey(doc.Data)
+		if err != nil {
+			results <- failed(file, doc.Index, err)
+		} else if ok && filter.Matches(apikey) {
+			jobs <- job{apikey: apikey, source: file, index: doc.Index}
+		}
 	}
-
-	wg.Wait()
-	return fileResults
 }
 
-func decryptKey(data []byte, key *rsa.PrivateKey) (string, string, error) {
+// parseKey parses a document and reports whether it is an API key resource.
+func parseKey(data []byte) (spec.APIKey, bool, error) {
 	var meta unversioned.TypeMeta
-
-	err := yaml.Unmarshal(data, &meta)
-	if err != nil {
-		return "", "", errors.New("yaml document did not contain Kubernetes resource")
+	if err := yaml.Unmarshal(data, &meta); err != nil {
+		return spec.APIKey{}, false, err
 	}
-
 	if meta.Kind != "ApiKey" {
-		return "", "", errors.New("yaml document was not an ApiKey")
+		return spec.APIKey{}, false, nil
 	}
 
 	var apikey spec.APIKey
 	if err := yaml.Unmarshal(data, &apikey); err != nil {
-		return "", "", err
+		return spec.APIKey{}, false, err
 	}
-
-	cipherText, err := hex.DecodeString(apikey.Spec.APIKeyData)
-	if err != nil {
-		return "", "", err
+	if len(apikey.ObjectMeta.Namespace) < 1 {
+		apikey.ObjectMeta.Namespace = cluster.DefaultNamespace
 	}
 
-	unecryptedData, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, key, cipherText, []byte(label))
-	if err != nil {
-		return apikey.ObjectMeta.Name, err.Error(), nil
+	return apikey, true, nil
+}
+
+func failed(source string, index int, err error) Result {
+	return Result{
+		Source:   source,
+		Document: index,
+		Status:   StatusFailed,
+		Error:    err.Error(),
 	}
-	return apikey.ObjectMeta.Name, string(unecryptedData), nil
 }
 
-func discoverFiles(inFilePath string) ([]string, error) {
-	fileList := []string{}
+func decryptKey(apikey spec.APIKey, source string, index int, key *rsa.PrivateKey) Result {
+	result := Result{
+		Name:      apikey.ObjectMeta.Name,
+		Namespace: apikey.ObjectMeta.Namespace,
+		Source:    source,
+		Document:  index,
+	}
 
-	err := filepath.Walk(inFilePath, func(path string, f os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		switch filepath.Ext(path) {
-		case ".yaml", ".yml", ".json":
-			fileList = append(fileList, path)
-		}
-		return nil
-	})
+	unecryptedData, err := KeyData(apikey.Spec.APIKeyData, key)
+	if err != nil {
+		result.Status = StatusFailed
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Status = StatusDecrypted
+	result.Key = string(unecryptedData)
+	return result
+}
 
+// KeyData decrypts the hex encoded data of an API key resource.
+func KeyData(encryptedKeyData string, key *rsa.PrivateKey) ([]byte, error) {
+	cipherText, err := hex.DecodeString(encryptedKeyData)
 	if err != nil {
 		return nil, err
 	}
 
-	return fileList, nil
+	return rsa.DecryptOAEP(sha256.New(), rand.Reader, key, cipherText, []byte(label))
+}
+
+type bySource []Result
+
+func (r bySource) Len() int      { return len(r) }
+func (r bySource) Swap(i, j int) { r[i], r[j] = r[j], r[i] }
+func (r bySource) Less(i, j int) bool {
+	if r[i].Source != r[j].Source {
+		return r[i].Source < r[j].Source
+	}
+	return r[i].Document < r[j].Document
 }
@@ -0,0 +1,190 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package decrypt
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/northwesternmutual/kanali/spec"
+	"github.com/northwesternmutual/kanalictl/pkg/generate"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "decrypt")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	_, foo, _, err := generate.Key("foo", "foodata", 0, generate.Policy{Alphabet: generate.DefaultAlphabet}, &privateKey.PublicKey)
+	assert.Nil(t, err)
+	_, bar, _, err := generate.Key("bar", "bardata", 0, generate.Policy{Alphabet: generate.DefaultAlphabet}, &otherKey.PublicKey)
+	assert.Nil(t, err)
+
+	var buf bytes.Buffer
+	buf.WriteString("apiVersion: kanali.io/v1\nkind: ApiProxy\nmetadata:\n  name: proxy\nspec:\n  path: /foo\n---\n")
+	write := func(name, namespace string, data []byte) {
+		d, err := yaml.Marshal(generate.CRD(name, generate.Metadata{Namespace: namespace}, data))
+		assert.Nil(t, err)
+		buf.Write(d)
+	}
+	write("foo", "a", foo)
+	buf.WriteString("---\n")
+	write("bar", "b", bar)
+	buf.WriteString("---\n")
+	write("baz", "", foo)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "keys.yaml"), buf.Bytes(), 0644))
+
+	results, err := Files(dir, privateKey, Filter{}, 4)
+	assert.Nil(t, err)
+	assert.Equal(t, len(results), 3)
+	assert.Equal(t, results[2].Namespace, "default")
+	assert.Equal(t, results[1].Name, "bar")
+	assert.Equal(t, results[1].Namespace, "b")
+	assert.Equal(t, results[1].Status, StatusFailed)
+	assert.NotEmpty(t, results[1].Error)
+	assert.Equal(t, results[1].Document, 2)
+	assert.Equal(t, Failed(results), 1)
+	assert.Equal(t, results[0], Result{
+		Name:      "foo",
+		Namespace: "a",
+		Source:    filepath.Join(dir, "keys.yaml"),
+		Document:  1,
+		Status:    StatusDecrypted,
+		Key:       "foodata",
+	})
+
+	results, err = Files(dir, privateKey, Filter{Namespace: "a"}, 4)
+	assert.Nil(t, err)
+	assert.Equal(t, len(results), 1)
+	assert.Equal(t, results[0].Name, "foo")
+
+	// an API key that does not name a namespace is in the default namespace
+	results, err = Files(dir, privateKey, Filter{Namespace: "default"}, 4)
+	assert.Nil(t, err)
+	assert.Equal(t, len(results), 1)
+	assert.Equal(t, results[0].Name, "baz")
+	assert.Equal(t, results[0].Key, "foodata")
+
+	results, err = Files(dir, privateKey, Filter{Names: []string{"bar", "qux"}}, 4)
+	assert.Nil(t, err)
+	assert.Equal(t, len(results), 1)
+	assert.Equal(t, results[0].Name, "bar")
+
+	_, err = Files(filepath.Join(dir, "missing"), privateKey, Filter{}, 4)
+	assert.NotNil(t, err)
+
+	_, err = Files(dir, privateKey, Filter{}, 0)
+	assert.Equal(t, err.Error(), "concurrency must be at least 1")
+}
+
+func TestFilesOrder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "decrypt")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	for _, file := range []string{"b.yaml", "a.yaml"} {
+		var buf bytes.Buffer
+		for i := 0; i < 10; i++ {
+			name := fmt.Sprintf("%s-%d", file[:1], i)
+			_, data, _, err := generate.Key(name, name, 0, generate.Policy{Alphabet: generate.DefaultAlphabet + "-"}, &privateKey.PublicKey)
+			assert.Nil(t, err)
+			d, err := yaml.Marshal(generate.CRD(name, generate.Metadata{Namespace: "default"}, data))
+			assert.Nil(t, err)
+			buf.Write(d)
+			buf.WriteString("---\n")
+		}
+		buf.WriteString("kind: ApiKey\nmetadata: [\n")
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, file), buf.Bytes(), 0644))
+	}
+
+	for _, concurrency := range []int{1, 3, 16} {
+		results, err := Files(dir, privateKey, Filter{}, concurrency)
+		assert.Nil(t, err)
+		assert.Equal(t, len(results), 22)
+		assert.Equal(t, Failed(results), 2)
+		for i, r := range results {
+			file, index := "a.yaml", i
+			if i > 10 {
+				file, index = "b.yaml", i-11
+			}
+			assert.Equal(t, r.Source, filepath.Join(dir, file))
+			assert.Equal(t, r.Document, index)
+			if index == 10 {
+				assert.Equal(t, r.Status, StatusFailed)
+				assert.Empty(t, r.Name)
+				assert.NotEmpty(t, r.Error)
+			} else {
+				assert.Equal(t, r.Key, fmt.Sprintf("%s-%d", file[:1], index))
+			}
+		}
+	}
+}
+
+func TestKeys(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	keys := []spec.APIKey{}
+	for _, name := range []string{"foo", "bar", "baz"} {
+		_, data, _, err := generate.Key(name, name+"data", 0, generate.Policy{Alphabet: generate.DefaultAlphabet}, &privateKey.PublicKey)
+		assert.Nil(t, err)
+		keys = append(keys, generate.CRD(name, generate.Metadata{Namespace: "default"}, data))
+	}
+	keys[1].Spec.APIKeyData = "zz"
+
+	results, err := Keys(keys, "cluster", privateKey, Filter{}, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, len(results), 3)
+	assert.Equal(t, results[0], Result{
+		Name:      "foo",
+		Namespace: "default",
+		Source:    "cluster",
+		Document:  0,
+		Status:    StatusDecrypted,
+		Key:       "foodata",
+	})
+	assert.Equal(t, results[1].Status, StatusFailed)
+	assert.Equal(t, results[2].Key, "bazdata")
+
+	results, err = Keys(keys, "cluster", privateKey, Filter{Names: []string{"baz"}}, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, len(results), 1)
+	assert.Equal(t, results[0].Document, 2)
+
+	_, err = Keys(keys, "cluster", privateKey, Filter{}, 0)
+	assert.NotNil(t, err)
+}
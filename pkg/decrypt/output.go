@@ -0,0 +1,164 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package decrypt
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/olekukonko/tablewriter"
+)
+
+// Format is a format results can be rendered in.
+type Format string
+
+const (
+	// FormatTable renders results as a human readable table.
+	FormatTable Format = "table"
+	// FormatJSON renders results as a JSON array.
+	FormatJSON Format = "json"
+	// FormatYAML renders results as a YAML list.
+	FormatYAML Format = "yaml"
+	// FormatCSV renders results as CSV with a header row.
+	FormatCSV Format = "csv"
+	// FormatEnv renders decrypted API keys as shell environment variables.
+	FormatEnv Format = "env"
+)
+
+var envInvalidRegex = regexp.MustCompile("[^A-Z0-9_]")
+
+// ParseFormat parses the name of an output format.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case FormatTable, FormatJSON, FormatYAML, FormatCSV, FormatEnv:
+		return f, nil
+	default:
+		return "", errors.New("output format must be one of table, json, yaml, csv or env")
+	}
+}
+
+// Render writes results to w in the given format.
+func Render(w io.Writer, format Format, results []Result) error {
+	switch format {
+	case FormatJSON:
+		data, err := json.MarshalIndent(results, "", "   ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+	case FormatYAML:
+		data, err := yaml.Marshal(results)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	case FormatCSV:
+		return renderCSV(w, results)
+	case FormatEnv:
+		return renderEnv(w, results)
+	default:
+		renderTable(w, results)
+		return nil
+	}
+}
+
+func renderTable(w io.Writer, results []Result) {
+	if len(results) < 1 {
+		fmt.Fprintln(w, "no API keys found")
+		return
+	}
+
+	table := tablewriter.NewWriter(w)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"API Key Name", "Namespace", "Status", "Result", "Source"})
+
+	for _, r := range results {
+		data := r.Key
+		status := "ok"
+		if r.Status != StatusDecrypted {
+			data = r.Error
+			status = "FAILED"
+		}
+		table.Append([]string{r.Name, r.Namespace, status, data, fmt.Sprintf("%s#%d", r.Source, r.Document)})
+	}
+
+	table.Render()
+}
+
+func renderCSV(w io.Writer, results []Result) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"name", "namespace", "source", "document", "status", "key", "error"}); err != nil {
+		return err
+	}
+	for _, r := range results {
+		if err := writer.Write([]string{r.Name, r.Namespace, r.Source, strconv.Itoa(r.Document), string(r.Status), r.Key, r.Error}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// renderEnv writes each decrypted API key as a variable assignment that can
+// be sourced by a POSIX shell. API keys that could not be decrypted are
+// written as comments so that they are not silently missing.
+func renderEnv(w io.Writer, results []Result) error {
+	seen := map[string]Result{}
+
+	for _, r := range results {
+		if r.Status != StatusDecrypted {
+			if _, err := fmt.Fprintf(w, "# %s could not be decrypted: %s\n", r.Name, r.Error); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := EnvName(r.Name)
+		if other, ok := seen[name]; ok {
+			return fmt.Errorf("ApiKeys %s in %s and %s in %s both map to %s - use --name or --namespace to select one", other.Name, other.Source, r.Name, r.Source, name)
+		}
+		seen[name] = r
+
+		if _, err := fmt.Fprintf(w, "%s='%s'\n", name, strings.Replace(r.Key, "'", `'\''`, -1)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// EnvName returns the environment variable an API key is written to.
+func EnvName(name string) string {
+	env := envInvalidRegex.ReplaceAllString(strings.ToUpper(name), "_")
+	if len(env) < 1 || (env[0] >= '0' && env[0] <= '9') {
+		env = "_" + env
+	}
+	return env
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package decrypt
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testResults = []Result{
+	{Name: "foo-bar", Namespace: "a", Source: "keys.yaml", Document: 0, Status: StatusDecrypted, Key: "abc"},
+	{Name: "baz", Namespace: "b", Source: "keys.yaml", Document: 1, Status: StatusFailed, Error: "crypto/rsa: decryption error"},
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, s := range []string{"table", "json", "yaml", "csv", "env", "JSON"} {
+		_, err := ParseFormat(s)
+		assert.Nil(t, err)
+	}
+	_, err := ParseFormat("xml")
+	assert.Equal(t, err.Error(), "output format must be one of table, json, yaml, csv or env")
+}
+
+func TestRender(t *testing.T) {
+	buf := &bytes.Buffer{}
+	assert.Nil(t, Render(buf, FormatJSON, testResults))
+	assert.Contains(t, buf.String(), `"status": "failed"`)
+	assert.Contains(t, buf.String(), `"error": "crypto/rsa: decryption error"`)
+
+	buf.Reset()
+	assert.Nil(t, Render(buf, FormatYAML, testResults))
+	assert.Contains(t, buf.String(), "- document: 0\n  key: abc\n  name: foo-bar\n  namespace: a\n  source: keys.yaml\n  status: decrypted\n")
+
+	buf.Reset()
+	assert.Nil(t, Render(buf, FormatCSV, testResults))
+	assert.Equal(t, buf.String(), "name,namespace,source,document,status,key,error\nfoo-bar,a,keys.yaml,0,decrypted,abc,\nbaz,b,keys.yaml,1,failed,,crypto/rsa: decryption error\n")
+
+	buf.Reset()
+	assert.Nil(t, Render(buf, FormatEnv, testResults))
+	assert.Equal(t, buf.String(), "FOO_BAR='abc'\n# baz could not be decrypted: crypto/rsa: decryption error\n")
+
+	buf.Reset()
+	assert.Nil(t, Render(buf, FormatTable, testResults))
+	assert.Contains(t, buf.String(), "FAILED")
+	assert.Contains(t, buf.String(), "keys.yaml#1")
+
+	buf.Reset()
+	assert.Nil(t, Render(buf, FormatTable, nil))
+	assert.Equal(t, buf.String(), "no API keys found\n")
+
+	duplicate := append(testResults, Result{Name: "foo.bar", Source: "other.yaml", Status: StatusDecrypted, Key: "def"})
+	assert.NotNil(t, Render(buf, FormatEnv, duplicate))
+}
+
+func TestEnvName(t *testing.T) {
+	assert.Equal(t, EnvName("foo-bar.baz"), "FOO_BAR_BAZ")
+	assert.Equal(t, EnvName("1foo"), "_1FOO")
+}
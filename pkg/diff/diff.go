@@ -0,0 +1,264 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/northwesternmutual/kanalictl/pkg/cluster"
+)
+
+const (
+	// Unchanged replaces ApiKey data that is the same in both versions.
+	Unchanged = "<unchanged>"
+	// Changed replaces ApiKey data that differs between versions.
+	Changed = "<changed>"
+	// Encrypted replaces the live ApiKey data when it differs.
+	Encrypted = "<encrypted>"
+
+	context = 3
+)
+
+// serverFields are metadata fields populated by the server which are never
+// part of a configuration.
+var serverFields = []string{
+	"uid",
+	"resourceVersion",
+	"selfLink",
+	"creationTimestamp",
+	"generation",
+	"deletionTimestamp",
+	"deletionGracePeriodSeconds",
+}
+
+// Prepare returns copies of the live and local versions of a resource
+// that can be compared. Fields populated by the server are removed and
+// the data of an ApiKey is replaced by whether it changed. A nil live
+// version, for a resource that does not exist yet, stays nil.
+func Prepare(live, local map[string]interface{}) (map[string]interface{}, map[string]interface{}, error) {
+	local, err := normalize(local)
+	if err != nil {
+		return nil, nil, err
+	}
+	if live != nil {
+		if live, err = normalize(live); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if kind, _ := local["kind"].(string); kind == "ApiKey" {
+		localSpec, _ := local["spec"].(map[string]interface{})
+		liveSpec, _ := live["spec"].(map[string]interface{})
+		if localSpec != nil {
+			if liveSpec != nil && liveSpec["data"] == localSpec["data"] {
+				localSpec["data"], liveSpec["data"] = Unchanged, Unchanged
+			} else {
+				localSpec["data"] = Changed
+				if liveSpec != nil {
+					liveSpec["data"] = Encrypted
+				}
+			}
+		}
+	}
+
+	return live, local, nil
+}
+
+// Lines returns a resource as sorted YAML split into lines. A nil
+// resource has no lines.
+func Lines(obj map[string]interface{}) ([]string, error) {
+	if obj == nil {
+		return nil, nil
+	}
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"), nil
+}
+
+// Unified returns a unified diff from a to b, or an empty string if they
+// are the same.
+func Unified(fromName, toName string, a, b []string) string {
+	ops := edits(a, b)
+
+	changed := false
+	for _, op := range ops {
+		if op.kind != ' ' {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return ""
+	}
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "--- %s\n+++ %s\n", fromName, toName)
+
+	for start := 0; start < len(ops); {
+		// find the next change and the run of changes, joined by no more
+		// than twice the context, that follows it
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		last := first
+		for i := first; i < len(ops); i++ {
+			if ops[i].kind != ' ' {
+				last = i
+			} else if i-last > 2*context {
+				break
+			}
+		}
+
+		begin := max(first-context, start)
+		end := min(last+context+1, len(ops))
+		writeHunk(buf, ops[begin:end])
+		start = end
+	}
+
+	return buf.String()
+}
+
+// op is a single line of an edit script.
+type op struct {
+	kind byte
+	line string
+	// a and b are the line numbers, counting from one, of the line in
+	// each version or, if the line is not in a version, of the line
+	// before it.
+	a, b int
+}
+
+// edits returns the shortest edit script turning a into b, found through
+// their longest common subsequence.
+func edits(a, b []string) []op {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	ops := []op{}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, op{kind: ' ', line: a[i], a: i + 1, b: j + 1})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, op{kind: '-', line: a[i], a: i + 1, b: j})
+			i++
+		default:
+			ops = append(ops, op{kind: '+', line: b[j], a: i, b: j + 1})
+			j++
+		}
+	}
+
+	return ops
+}
+
+func writeHunk(buf *bytes.Buffer, ops []op) {
+	aStart, aLen, bStart, bLen := 0, 0, 0, 0
+	for _, op := range ops {
+		if op.kind != '+' {
+			if aLen == 0 {
+				aStart = op.a
+			}
+			aLen++
+		}
+		if op.kind != '-' {
+			if bLen == 0 {
+				bStart = op.b
+			}
+			bLen++
+		}
+	}
+	if aLen == 0 {
+		aStart = ops[0].a
+	}
+	if bLen == 0 {
+		bStart = ops[0].b
+	}
+
+	fmt.Fprintf(buf, "@@ -%d,%d +%d,%d @@\n", aStart, aLen, bStart, bLen)
+	for _, op := range ops {
+		fmt.Fprintf(buf, "%c%s\n", op.kind, op.line)
+	}
+}
+
+// normalize returns a deep copy of a resource without the fields populated
+// by the server.
+func normalize(obj map[string]interface{}) (map[string]interface{}, error) {
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	copied := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &copied); err != nil {
+		return nil, err
+	}
+
+	delete(copied, "status")
+	if metadata, ok := copied["metadata"].(map[string]interface{}); ok {
+		for _, field := range serverFields {
+			delete(metadata, field)
+		}
+		if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+			delete(annotations, cluster.LastAppliedAnnotation)
+			if len(annotations) < 1 {
+				delete(metadata, "annotations")
+			}
+		}
+	}
+
+	return copied, nil
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package diff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrepare(t *testing.T) {
+	assert := assert.New(t)
+
+	live := map[string]interface{}{
+		"kind": "ApiProxy",
+		"metadata": map[string]interface{}{
+			"name":              "foo",
+			"namespace":         "default",
+			"uid":               "1234",
+			"resourceVersion":   "42",
+			"selfLink":          "/apis/kanali.io/v1/namespaces/default/apiproxies/foo",
+			"creationTimestamp": "2017-01-01T00:00:00Z",
+			"annotations": map[string]interface{}{
+				"kubectl.kubernetes.io/last-applied-configuration": "{}",
+			},
+		},
+		"spec": map[string]interface{}{"path": "/foo"},
+	}
+	local := map[string]interface{}{
+		"kind": "ApiProxy",
+		"metadata": map[string]interface{}{
+			"name":      "foo",
+			"namespace": "default",
+		},
+		"spec": map[string]interface{}{"path": "/foo"},
+	}
+
+	preparedLive, preparedLocal, err := Prepare(live, local)
+	assert.Nil(err)
+	assert.Equal(preparedLocal, preparedLive)
+	assert.Equal("42", live["metadata"].(map[string]interface{})["resourceVersion"], "live version must not be modified")
+
+	_, preparedLocal, err = Prepare(nil, local)
+	assert.Nil(err)
+	assert.Equal(local, preparedLocal)
+}
+
+func TestPrepareAPIKey(t *testing.T) {
+	assert := assert.New(t)
+
+	apikey := func(data string) map[string]interface{} {
+		return map[string]interface{}{
+			"kind":     "ApiKey",
+			"metadata": map[string]interface{}{"name": "foo"},
+			"spec":     map[string]interface{}{"data": data},
+		}
+	}
+	data := func(obj map[string]interface{}) interface{} {
+		return obj["spec"].(map[string]interface{})["data"]
+	}
+
+	live, local, err := Prepare(apikey("abc"), apikey("abc"))
+	assert.Nil(err)
+	assert.Equal(Unchanged, data(live))
+	assert.Equal(Unchanged, data(local))
+
+	live, local, err = Prepare(apikey("abc"), apikey("def"))
+	assert.Nil(err)
+	assert.Equal(Encrypted, data(live))
+	assert.Equal(Changed, data(local))
+
+	live, local, err = Prepare(nil, apikey("def"))
+	assert.Nil(err)
+	assert.Nil(live)
+	assert.Equal(Changed, data(local))
+}
+
+func TestUnified(t *testing.T) {
+	assert := assert.New(t)
+
+	a := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l"}
+
+	assert.Equal("", Unified("a", "b", a, a))
+
+	b := []string{"a", "B", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l", "m"}
+	assert.Equal(`--- a
++++ b
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -10,3 +10,4 @@
+ j
+ k
+ l
++m
+`, Unified("a", "b", a, b))
+
+	assert.Equal(`--- a
++++ b
+@@ -0,0 +1,2 @@
++a
++b
+`, Unified("a", "b", nil, []string{"a", "b"}))
+}
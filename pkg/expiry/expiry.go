@@ -0,0 +1,329 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package expiry
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/northwesternmutual/kanali/spec"
+	"github.com/northwesternmutual/kanalictl/pkg/manifest"
+	"github.com/olekukonko/tablewriter"
+)
+
+const (
+	// AnnotationCreatedAt records when an API key was generated.
+	AnnotationCreatedAt = "kanali.io/created-at"
+	// AnnotationExpiresAt records when an API key expires.
+	AnnotationExpiresAt = "kanali.io/expires-at"
+	// AnnotationOwner records who owns an API key.
+	AnnotationOwner = "kanali.io/owner"
+	// AnnotationContact records how to reach the owner of an API key.
+	AnnotationContact = "kanali.io/contact"
+)
+
+// Status describes why an API key appears in a report.
+type Status string
+
+const (
+	// StatusExpired is the status of API keys that have expired.
+	StatusExpired Status = "expired"
+	// StatusExpiring is the status of API keys that expire soon.
+	StatusExpiring Status = "expiring"
+	// StatusRotationDue is the status of API keys that are older than the
+	// rotation age.
+	StatusRotationDue Status = "rotation due"
+	// StatusInvalid is the status of API keys whose lifecycle annotations
+	// cannot be parsed.
+	StatusInvalid Status = "invalid"
+)
+
+// Lifecycle describes the lifecycle of a generated API key.
+type Lifecycle struct {
+	ExpiresIn time.Duration
+	Owner     string
+	Contact   string
+}
+
+// Annotations returns the annotations recording the lifecycle of an API
+// key generated at now.
+func (l Lifecycle) Annotations(now time.Time) map[string]string {
+	annotations := map[string]string{
+		AnnotationCreatedAt: now.UTC().Format(time.RFC3339),
+	}
+	if l.ExpiresIn > 0 {
+		annotations[AnnotationExpiresAt] = now.Add(l.ExpiresIn).UTC().Format(time.RFC3339)
+	}
+	if len(l.Owner) > 0 {
+		annotations[AnnotationOwner] = l.Owner
+	}
+	if len(l.Contact) > 0 {
+		annotations[AnnotationContact] = l.Contact
+	}
+	return annotations
+}
+
+// Renew returns the annotations of an API key that replaces an API key with
+// the given annotations at now. The replacement is given the same lifetime
+// as the API key it replaces.
+func Renew(annotations map[string]string, now time.Time) map[string]string {
+	renewed := make(map[string]string, len(annotations)+1)
+	for k, v := range annotations {
+		renewed[k] = v
+	}
+	renewed[AnnotationCreatedAt] = now.UTC().Format(time.RFC3339)
+
+	expires, err := time.Parse(time.RFC3339, annotations[AnnotationExpiresAt])
+	if err != nil {
+		return renewed
+	}
+	created, err := time.Parse(time.RFC3339, annotations[AnnotationCreatedAt])
+	if err != nil || !expires.After(created) {
+		delete(renewed, AnnotationExpiresAt)
+		return renewed
+	}
+	renewed[AnnotationExpiresAt] = now.Add(expires.Sub(created)).UTC().Format(time.RFC3339)
+
+	return renewed
+}
+
+// ParseDuration parses a duration such as 30d, 2w or 12h. In addition to
+// the units understood by time.ParseDuration, d (days) and w (weeks) are
+// supported.
+func ParseDuration(s string) (time.Duration, error) {
+	unit := time.Duration(0)
+	switch {
+	case strings.HasSuffix(s, "d"):
+		unit = 24 * time.Hour
+	case strings.HasSuffix(s, "w"):
+		unit = 7 * 24 * time.Hour
+	default:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %s", s)
+		}
+		return d, nil
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(s[:len(s)-1]))
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid duration %s", s)
+	}
+	return time.Duration(n) * unit, nil
+}
+
+// Located is an API key along with where it was found.
+type Located struct {
+	Key    spec.APIKey
+	Source string
+}
+
+// Entry describes an API key that has expired, expires soon, is due for
+// rotation or has invalid lifecycle annotations.
+type Entry struct {
+	Name      string
+	Namespace string
+	Source    string
+	Owner     string
+	Contact   string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	Status    Status
+	Problem   string
+}
+
+// Report returns the API keys that expired before now or expire within the
+// given duration. If rotationAge is greater than zero, API keys that are
+// older than it are also returned. Entries are sorted by expiry.
+func Report(keys []Located, now time.Time, within, rotationAge time.Duration) []Entry {
+	entries := []Entry{}
+
+	for _, located := range keys {
+		meta := located.Key.ObjectMeta
+		entry := Entry{
+			Name:      meta.Name,
+			Namespace: meta.Namespace,
+			Source:    located.Source,
+			Owner:     meta.Annotations[AnnotationOwner],
+			Contact:   meta.Annotations[AnnotationContact],
+			CreatedAt: meta.CreationTimestamp.Time,
+		}
+
+		if created, ok := meta.Annotations[AnnotationCreatedAt]; ok {
+			t, err := time.Parse(time.RFC3339, created)
+			if err != nil {
+				entry.Status = StatusInvalid
+				entry.Problem = fmt.Sprintf("annotation %s is not an RFC 3339 time", AnnotationCreatedAt)
+				entries = append(entries, entry)
+				continue
+			}
+			entry.CreatedAt = t
+		}
+
+		if expires, ok := meta.Annotations[AnnotationExpiresAt]; ok {
+			t, err := time.Parse(time.RFC3339, expires)
+			if err != nil {
+				entry.Status = StatusInvalid
+				entry.Problem = fmt.Sprintf("annotation %s is not an RFC 3339 time", AnnotationExpiresAt)
+				entries = append(entries, entry)
+				continue
+			}
+			entry.ExpiresAt = t
+		}
+
+		switch {
+		case !entry.ExpiresAt.IsZero() && !entry.ExpiresAt.After(now):
+			entry.Status = StatusExpired
+		case !entry.ExpiresAt.IsZero() && !entry.ExpiresAt.After(now.Add(within)):
+			entry.Status = StatusExpiring
+		case rotationAge > 0 && !entry.CreatedAt.IsZero() && !entry.CreatedAt.After(now.Add(-rotationAge)):
+			entry.Status = StatusRotationDue
+		default:
+			continue
+		}
+
+		entries = append(entries, entry)
+	}
+
+	sort.Stable(byExpiry(entries))
+
+	return entries
+}
+
+// Expired returns the entries of API keys that have expired.
+func Expired(entries []Entry) []Entry {
+	expired := []Entry{}
+	for _, entry := range entries {
+		if entry.Status == StatusExpired {
+			expired = append(expired, entry)
+		}
+	}
+	return expired
+}
+
+// Unbind removes the named API keys from the given ApiKeyBindings and
+// returns the ApiKeyBindings that changed.
+func Unbind(bindings []spec.APIKeyBinding, names []string) []spec.APIKeyBinding {
+	remove := map[string]bool{}
+	for _, name := range names {
+		remove[name] = true
+	}
+
+	changed := []spec.APIKeyBinding{}
+	for _, binding := range bindings {
+		keys := make([]spec.Key, 0, len(binding.Spec.Keys))
+		for _, key := range binding.Spec.Keys {
+			if !remove[key.Name] {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) == len(binding.Spec.Keys) {
+			continue
+		}
+		binding.Spec.Keys = keys
+		changed = append(changed, binding)
+	}
+
+	return changed
+}
+
+// FromFiles returns every API key found under path.
+func FromFiles(path string) ([]Located, error) {
+	fileList, err := manifest.Discover(path)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []Located{}
+	for _, file := range fileList {
+		f, err := manifest.Read(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, doc := range f.Documents {
+			if doc.Kind() != "ApiKey" {
+				continue
+			}
+			var key spec.APIKey
+			if err := yaml.Unmarshal(doc.Data, &key); err != nil {
+				return nil, fmt.Errorf("%s: %s", file, err.Error())
+			}
+			keys = append(keys, Located{Key: key, Source: file})
+		}
+	}
+
+	return keys, nil
+}
+
+// Render writes entries to w as a table.
+func Render(w io.Writer, entries []Entry) {
+	if len(entries) < 1 {
+		fmt.Fprintln(w, "no expired, expiring or rotation due API keys found")
+		return
+	}
+
+	table := tablewriter.NewWriter(w)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"API Key Name", "Namespace", "Owner", "Contact", "Created", "Expires", "Status", "Source"})
+
+	for _, entry := range entries {
+		status := string(entry.Status)
+		if len(entry.Problem) > 0 {
+			status = fmt.Sprintf("%s: %s", status, entry.Problem)
+		}
+		table.Append([]string{
+			entry.Name,
+			entry.Namespace,
+			entry.Owner,
+			entry.Contact,
+			formatTime(entry.CreatedAt),
+			formatTime(entry.ExpiresAt),
+			status,
+			entry.Source,
+		})
+	}
+
+	table.Render()
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// byExpiry orders entries by expiry with entries that never expire last.
+type byExpiry []Entry
+
+func (e byExpiry) Len() int      { return len(e) }
+func (e byExpiry) Swap(i, j int) { e[i], e[j] = e[j], e[i] }
+func (e byExpiry) Less(i, j int) bool {
+	if e[i].ExpiresAt.IsZero() != e[j].ExpiresAt.IsZero() {
+		return e[j].ExpiresAt.IsZero()
+	}
+	return e[i].ExpiresAt.Before(e[j].ExpiresAt)
+}
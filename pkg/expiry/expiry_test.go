@@ -0,0 +1,186 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package expiry
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/northwesternmutual/kanali/spec"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/api/unversioned"
+)
+
+func TestParseDuration(t *testing.T) {
+	for s, d := range map[string]time.Duration{
+		"30d": 30 * 24 * time.Hour,
+		"2w":  14 * 24 * time.Hour,
+		"12h": 12 * time.Hour,
+		"0d":  0,
+	} {
+		parsed, err := ParseDuration(s)
+		assert.Nil(t, err)
+		assert.Equal(t, parsed, d, s)
+	}
+
+	for _, s := range []string{"", "d", "-1d", "thirty days", "1.5w"} {
+		_, err := ParseDuration(s)
+		assert.NotNil(t, err, s)
+	}
+}
+
+func TestLifecycleAnnotations(t *testing.T) {
+	now := time.Date(2017, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, Lifecycle{}.Annotations(now), map[string]string{
+		AnnotationCreatedAt: "2017-07-01T12:00:00Z",
+	})
+
+	assert.Equal(t, Lifecycle{ExpiresIn: 30 * 24 * time.Hour, Owner: "team-a", Contact: "team-a@example.com"}.Annotations(now), map[string]string{
+		AnnotationCreatedAt: "2017-07-01T12:00:00Z",
+		AnnotationExpiresAt: "2017-07-31T12:00:00Z",
+		AnnotationOwner:     "team-a",
+		AnnotationContact:   "team-a@example.com",
+	})
+}
+
+func TestRenew(t *testing.T) {
+	now := time.Date(2017, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, Renew(map[string]string{
+		AnnotationCreatedAt: "2017-01-01T00:00:00Z",
+		AnnotationExpiresAt: "2017-01-31T00:00:00Z",
+		AnnotationOwner:     "team-a",
+	}, now), map[string]string{
+		AnnotationCreatedAt: "2017-07-01T12:00:00Z",
+		AnnotationExpiresAt: "2017-07-31T12:00:00Z",
+		AnnotationOwner:     "team-a",
+	})
+
+	assert.Equal(t, Renew(map[string]string{
+		AnnotationExpiresAt: "2017-01-31T00:00:00Z",
+	}, now), map[string]string{
+		AnnotationCreatedAt: "2017-07-01T12:00:00Z",
+	})
+
+	assert.Equal(t, Renew(nil, now), map[string]string{
+		AnnotationCreatedAt: "2017-07-01T12:00:00Z",
+	})
+}
+
+func TestUnbind(t *testing.T) {
+	binding := func(name string, keys ...string) spec.APIKeyBinding {
+		b := spec.APIKeyBinding{ObjectMeta: api.ObjectMeta{Name: name}}
+		for _, key := range keys {
+			b.Spec.Keys = append(b.Spec.Keys, spec.Key{Name: key})
+		}
+		return b
+	}
+
+	changed := Unbind([]spec.APIKeyBinding{
+		binding("one", "foo", "bar"),
+		binding("two", "bar"),
+		binding("three", "baz", "foo"),
+	}, []string{"foo", "qux"})
+
+	assert.Equal(t, changed, []spec.APIKeyBinding{
+		binding("one", "bar"),
+		binding("three", "baz"),
+	})
+}
+
+func TestReport(t *testing.T) {
+	now := time.Date(2017, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	key := func(name string, annotations map[string]string, created time.Time) Located {
+		return Located{
+			Key: spec.APIKey{ObjectMeta: api.ObjectMeta{
+				Name:              name,
+				Namespace:         "default",
+				Annotations:       annotations,
+				CreationTimestamp: unversioned.NewTime(created),
+			}},
+			Source: "cluster",
+		}
+	}
+
+	keys := []Located{
+		key("later", map[string]string{AnnotationExpiresAt: "2017-12-01T00:00:00Z"}, time.Time{}),
+		key("soon", map[string]string{AnnotationExpiresAt: "2017-07-20T00:00:00Z", AnnotationOwner: "team-a"}, time.Time{}),
+		key("expired", map[string]string{AnnotationExpiresAt: "2017-06-01T00:00:00Z"}, time.Time{}),
+		key("old", nil, now.Add(-100*24*time.Hour)),
+		key("old-annotated", map[string]string{AnnotationCreatedAt: "2017-01-01T00:00:00Z"}, now),
+		key("new", nil, now.Add(-10*24*time.Hour)),
+		key("broken", map[string]string{AnnotationExpiresAt: "tomorrow"}, time.Time{}),
+	}
+
+	entries := Report(keys, now, 30*24*time.Hour, 90*24*time.Hour)
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Name)
+	}
+	assert.Equal(t, names, []string{"expired", "soon", "old", "old-annotated", "broken"})
+	assert.Equal(t, entries[0].Status, StatusExpired)
+	assert.Equal(t, entries[1].Status, StatusExpiring)
+	assert.Equal(t, entries[1].Owner, "team-a")
+	assert.Equal(t, entries[2].Status, StatusRotationDue)
+	assert.Equal(t, entries[3].CreatedAt, time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, entries[4].Status, StatusInvalid)
+
+	entries = Report(keys, now, 0, 0)
+	assert.Equal(t, len(entries), 2)
+	assert.Equal(t, Expired(entries)[0].Name, "expired")
+}
+
+func TestFromFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "expiry")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "keys.yaml"), []byte(`apiVersion: kanali.io/v1
+kind: ApiKey
+metadata:
+  name: foo
+  annotations:
+    kanali.io/expires-at: "2017-06-01T00:00:00Z"
+spec:
+  data: ab
+---
+apiVersion: kanali.io/v1
+kind: ApiKeyBinding
+metadata:
+  name: foo
+spec:
+  proxy: foo
+  keys:
+  - name: foo
+`), 0644))
+
+	keys, err := FromFiles(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, len(keys), 1)
+	assert.Equal(t, keys[0].Key.ObjectMeta.Name, "foo")
+	assert.Equal(t, keys[0].Source, filepath.Join(dir, "keys.yaml"))
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package generate
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/northwesternmutual/kanalictl/pkg/handoff"
+)
+
+// CheckDeliveryFile ensures that unencrypted API keys can be written to
+// path. An existing delivery file is never overwritten.
+func CheckDeliveryFile(path string) error {
+	if _, err := deliveryFormat(path); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%s already exists - no api keys were generated", path)
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// WriteDelivery writes the unencrypted API keys to a single file that can be
+// handed off to the consumers of the keys. If recipients are provided, the
+// file is encrypted for them and ASCII armored. The file is only readable by
+// its owner and will never overwrite an existing file.
+func WriteDelivery(path string, keys []GeneratedKey, recipients *handoff.Recipients) error {
+	format, err := deliveryFormat(path)
+	if err != nil {
+		return err
+	}
+
+	data, err := marshalDelivery(format, keys)
+	if err != nil {
+		return err
+	}
+
+	if recipients.Len() > 0 {
+		if data, err = recipients.Encrypt(data); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// deliveryFormat returns the format of a delivery file. An .asc or .age
+// extension, as used for encrypted delivery files, is ignored when
+// determining the format and YAML is used if nothing else remains.
+func deliveryFormat(path string) (string, error) {
+	ext := filepath.Ext(path)
+	if ext == ".asc" || ext == ".age" {
+		if ext = filepath.Ext(strings.TrimSuffix(path, ext)); len(ext) < 1 {
+			ext = ".yaml"
+		}
+	}
+
+	switch ext {
+	case ".csv", ".yaml", ".yml", ".json":
+		return ext, nil
+	default:
+		return "", errors.New("delivery file must be either csv, json or yaml format")
+	}
+}
+
+func marshalDelivery(format string, keys []GeneratedKey) ([]byte, error) {
+	if format == ".csv" {
+		buf := &bytes.Buffer{}
+		w := csv.NewWriter(buf)
+		if err := w.Write([]string{"name", "namespace", "key"}); err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			if err := w.Write([]string{key.CRD.ObjectMeta.Name, key.CRD.ObjectMeta.Namespace, string(key.UnencryptedKeyData)}); err != nil {
+				return nil, err
+			}
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	delivery := make([]map[string]string, 0, len(keys))
+	for _, key := range keys {
+		delivery = append(delivery, map[string]string{
+			"name":      key.CRD.ObjectMeta.Name,
+			"namespace": key.CRD.ObjectMeta.Namespace,
+			"key":       string(key.UnencryptedKeyData),
+		})
+	}
+
+	if format == ".json" {
+		return json.MarshalIndent(delivery, "", "   ")
+	}
+	return yaml.Marshal(delivery)
+}
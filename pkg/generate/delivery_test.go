@@ -0,0 +1,86 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package generate
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/northwesternmutual/kanali/spec"
+	"github.com/northwesternmutual/kanalictl/pkg/handoff"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/kubernetes/pkg/api"
+)
+
+func TestDeliveryFormat(t *testing.T) {
+	for path, format := range map[string]string{
+		"keys.csv":      ".csv",
+		"keys.json":     ".json",
+		"keys.csv.age":  ".csv",
+		"keys.json.asc": ".json",
+		"keys.asc":      ".yaml",
+	} {
+		f, err := deliveryFormat(path)
+		assert.Nil(t, err)
+		assert.Equal(t, f, format, path)
+	}
+
+	_, err := deliveryFormat("keys.txt")
+	assert.Equal(t, err.Error(), "delivery file must be either csv, json or yaml format")
+	_, err = deliveryFormat("keys.txt.age")
+	assert.NotNil(t, err)
+}
+
+func TestWriteDeliveryEncrypted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "delivery")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	identity, err := age.GenerateX25519Identity()
+	assert.Nil(t, err)
+	recipients, err := handoff.LoadRecipients([]string{identity.Recipient().String()}, nil)
+	assert.Nil(t, err)
+
+	keys := []GeneratedKey{{
+		CRD:                spec.APIKey{ObjectMeta: api.ObjectMeta{Name: "foo", Namespace: "default"}},
+		UnencryptedKeyData: []byte("abc123"),
+	}}
+
+	path := filepath.Join(dir, "keys.csv.age")
+	assert.Nil(t, CheckDeliveryFile(path))
+	assert.Nil(t, WriteDelivery(path, keys, recipients))
+	assert.Equal(t, CheckDeliveryFile(path).Error(), path+" already exists - no api keys were generated")
+
+	data, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	assert.False(t, bytes.Contains(data, []byte("abc123")))
+
+	plaintext, err := age.Decrypt(armor.NewReader(bytes.NewReader(data)), identity)
+	assert.Nil(t, err)
+	data, err = ioutil.ReadAll(plaintext)
+	assert.Nil(t, err)
+	assert.Equal(t, string(data), "name,namespace,key\nfoo,default,abc123\n")
+}
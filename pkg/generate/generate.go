@@ -27,57 +27,76 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
-	mathRand "math/rand"
+	"math/big"
 	"os"
 	"path/filepath"
 	"regexp"
-	"time"
 
 	"github.com/ghodss/yaml"
 	"github.com/northwesternmutual/kanali/spec"
-)
-
-var (
-	src = mathRand.NewSource(time.Now().UnixNano())
+	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/api/unversioned"
 )
 
 const (
-	keyNameRegex  = "^[a-z0-9]([-a-z0-9]*[a-z0-9])?$"
-	keyDataRegex  = "^[0-9a-zA-Z]+$"
-	letterBytes   = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
-	letterIdxBits = 6                    // 6 bits to represent a letter index
-	letterIdxMask = 1<<letterIdxBits - 1 // All 1-bits, as many as letterIdxBits
-	letterIdxMax  = 63 / letterIdxBits   // # of letter indices fitting in 63 bits
-	label         = "kanali"
+	keyNameRegex = "^[a-z0-9]([-a-z0-9]*[a-z0-9])?$"
+	label        = "kanali"
+	// maxKeyDataAttempts is the number of times key data is drawn before
+	// giving up on finding key data that satisfies the policy estimate.
+	maxKeyDataAttempts = 100
 )
 
-// Key generate an encrypted key. It produces both the
-// unencrypted key as well as the encrypted key data.
-func Key(keyName, existingKey string, length int, encryptKey *rsa.PublicKey) ([]byte, []byte, error) {
+// Key generate an encrypted key. It produces the unencrypted key,
+// the encrypted key data and the entropy, in bits, of the unencrypted key.
+func Key(keyName, existingKey string, length int, policy Policy, encryptKey *rsa.PublicKey) ([]byte, []byte, float64, error) {
 
 	if !regexp.MustCompile(keyNameRegex).MatchString(keyName) {
-		return nil, nil, fmt.Errorf("key name must conform to the pattern %s", keyNameRegex)
+		return nil, nil, 0, fmt.Errorf("key name must conform to the pattern %s", keyNameRegex)
 	}
 
-	unencryptedKeyData, err := generateKeyData(existingKey, length)
+	unencryptedKeyData, entropy, err := generateKeyData(existingKey, length, policy)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, 0, err
 	}
 
-	encryptedKeyData, err := encryptKeyData(unencryptedKeyData, encryptKey)
+	encryptedKeyData, err := EncryptKeyData(unencryptedKeyData, encryptKey)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, 0, err
 	}
 
-	return unencryptedKeyData, encryptedKeyData, nil
+	return unencryptedKeyData, encryptedKeyData, entropy, nil
+
+}
 
+// CRD creates the Kubernetes config for an API key.
+func CRD(name string, meta Metadata, encryptedKeyData []byte) spec.APIKey {
+	return spec.APIKey{
+		TypeMeta: unversioned.TypeMeta{
+			APIVersion: "kanali.io/v1",
+			Kind:       "ApiKey",
+		},
+		ObjectMeta: api.ObjectMeta{
+			Name:        name,
+			Namespace:   meta.Namespace,
+			Labels:      meta.Labels,
+			Annotations: meta.Annotations,
+		},
+		Spec: spec.APIKeySpec{
+			APIKeyData: fmt.Sprintf("%x", encryptedKeyData),
+		},
+	}
 }
 
-// Display will display an API key an the corresponding Kubernetes config
-func Display(showCRD bool, unencryptedKeyData []byte, keyCRD spec.APIKey) error {
+// Display will display an API key an the corresponding Kubernetes config.
+// The API key itself is not displayed if unencryptedKeyData is nil.
+func Display(w io.Writer, showCRD bool, unencryptedKeyData []byte, entropy float64, keyCRD spec.APIKey) error {
 
-	fmt.Printf("Here is your api key (you will only see this once): %s\n", string(unencryptedKeyData))
+	if unencryptedKeyData != nil {
+		fmt.Fprintf(w, "Here is your api key (you will only see this once): %s\n", string(unencryptedKeyData))
+	}
+	fmt.Fprintf(w, "Entropy of api key: %.1f bits\n", entropy)
 
 	if !showCRD {
 		return nil
@@ -88,22 +107,31 @@ func Display(showCRD bool, unencryptedKeyData []byte, keyCRD spec.APIKey) error
 		return err
 	}
 
-	fmt.Printf("Corresponding Kubernetes config:\n%s", string(yamlData))
+	fmt.Fprintf(w, "Corresponding Kubernetes config:\n%s", string(yamlData))
 	return nil
 }
 
-// Write will write the corresponding Kubernetes config to a file.
-func Write(outFileName string, keyCRD spec.APIKey) error {
+// Write will write the corresponding Kubernetes config to a file. The
+// overwrite mode should be the one returned by CheckOutFile.
+func Write(outFileName string, overwrite Overwrite, keyCRD spec.APIKey) error {
 	if len(outFileName) < 1 {
 		return nil
 	}
 
-	_, err := os.Stat(outFileName)
-	if err != nil && !os.IsNotExist(err) {
+	if outFileName == Stdout {
+		yamlData, err := yaml.Marshal(keyCRD)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(yamlData)
 		return err
 	}
 
-	if !os.IsNotExist(err) && shouldNotOverwrite(outFileName) {
+	if overwrite == OverwriteAppend {
+		if err := appendTo(outFileName, keyCRD); err != nil {
+			return err
+		}
+		fmt.Printf("Corresponding Kubernetes config appended to %s\n", outFileName)
 		return nil
 	}
 
@@ -131,22 +159,9 @@ func Write(outFileName string, keyCRD spec.APIKey) error {
 	return nil
 }
 
-func shouldNotOverwrite(outFileName string) bool {
-	var input string
-
-	for {
-		fmt.Printf("%s exists - do you want to override it? (Y/n) ", outFileName)
-		fmt.Scanln(&input)
-		switch input {
-		case "Y":
-			return false
-		case "n":
-			return true
-		}
-	}
-}
-
-func encryptKeyData(unencryptedKeyData []byte, encryptKey *rsa.PublicKey) ([]byte, error) {
+// EncryptKeyData encrypts API key data so that it can only
+// be decrypted by the holder of the corresponding private key.
+func EncryptKeyData(unencryptedKeyData []byte, encryptKey *rsa.PublicKey) ([]byte, error) {
 	if encryptKey == nil {
 		return nil, errors.New("no public key provided")
 	}
@@ -154,31 +169,79 @@ func encryptKeyData(unencryptedKeyData []byte, encryptKey *rsa.PublicKey) ([]byt
 	return rsa.EncryptOAEP(sha256.New(), cryptoRand.Reader, encryptKey, unencryptedKeyData, []byte(label))
 }
 
-func generateKeyData(existingKey string, length int) ([]byte, error) {
+// generateKeyData returns either the existing key data or newly generated
+// key data, along with its entropy, provided it satisfies the given policy.
+func generateKeyData(existingKey string, length int, policy Policy) ([]byte, float64, error) {
+	entropy, err := checkKeyData(existingKey, length, policy)
+	if err != nil {
+		return nil, 0, err
+	}
+
 	if len(existingKey) > 0 {
-		if !regexp.MustCompile(keyDataRegex).MatchString(existingKey) {
-			return nil, fmt.Errorf("key data must conform to the pattern %s", keyDataRegex)
+		return []byte(existingKey), entropy, nil
+	}
+
+	// Random key data can contain a run of repeated or sequential characters,
+	// which the policy estimate does not credit. Such key data is drawn again
+	// so that every generated key would also be accepted as existing key data.
+	for attempt := 0; attempt < maxKeyDataAttempts; attempt++ {
+		b, err := randomKeyData(length, policy.Alphabet)
+		if err != nil {
+			return nil, 0, err
+		}
+		if policy.EstimateEntropy(b) >= policy.MinEntropy {
+			return b, entropy, nil
 		}
+	}
+
+	return nil, 0, fmt.Errorf("could not generate key data of length %d with an estimated entropy of at least %.1f bits - increase the key length", length, policy.MinEntropy)
+}
+
+// randomKeyData returns key data of the given length drawn uniformly at
+// random from the given alphabet.
+func randomKeyData(length int, alphabet string) ([]byte, error) {
+	alphabetSize := big.NewInt(int64(len(alphabet)))
 
-		return []byte(existingKey), nil
+	b := make([]byte, length)
+	for i := range b {
+		idx, err := cryptoRand.Int(cryptoRand.Reader, alphabetSize)
+		if err != nil {
+			return nil, err
+		}
+		b[i] = alphabet[idx.Int64()]
 	}
 
-	if len(existingKey) < 1 && length < 1 {
-		return nil, errors.New("key length must be an greater than zero")
+	return b, nil
+}
+
+// checkKeyData ensures that either the existing key data or key data of the
+// given length satisfies the given policy. It returns the entropy of the key data.
+func checkKeyData(existingKey string, length int, policy Policy) (float64, error) {
+	if err := policy.Validate(); err != nil {
+		return 0, err
 	}
 
-	b := make([]byte, length)
-	for i, cache, remain := length-1, src.Int63(), letterIdxMax; i >= 0; {
-		if remain == 0 {
-			cache, remain = src.Int63(), letterIdxMax
+	if len(existingKey) > 0 {
+		if !policy.allows(existingKey) {
+			return 0, fmt.Errorf("key data must only contain characters from the alphabet %s", policy.Alphabet)
 		}
-		if idx := int(cache & letterIdxMask); idx < len(letterBytes) {
-			b[i] = letterBytes[idx]
-			i--
+
+		entropy := policy.EstimateEntropy([]byte(existingKey))
+		if entropy < policy.MinEntropy {
+			return 0, fmt.Errorf("key data has an estimated entropy of %.1f bits - at least %.1f bits are required", entropy, policy.MinEntropy)
 		}
-		cache >>= letterIdxBits
-		remain--
+
+		return entropy, nil
 	}
 
-	return b, nil
+	if length < 1 {
+		return 0, errors.New("key length must be an greater than zero")
+	}
+
+	entropy := policy.Entropy(length)
+	if entropy < policy.MinEntropy {
+		return 0, fmt.Errorf("key length %d provides %.1f bits of entropy - at least %.1f bits are required", length, entropy, policy.MinEntropy)
+	}
+
+	return entropy, nil
 }
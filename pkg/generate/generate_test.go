@@ -21,20 +21,109 @@
 package generate
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"math"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func TestGenerateKeyData(t *testing.T) {
-	result, _ := generateKeyData("foo", 1)
+	policy := Policy{
+		Alphabet:   DefaultAlphabet,
+		MinEntropy: 0,
+	}
+
+	result, _, _ := generateKeyData("foo", 1, policy)
 	assert.Equal(t, result, []byte("foo"))
 
-	_, err := generateKeyData("", 0)
+	_, _, err := generateKeyData("", 0, policy)
 	assert.Equal(t, err.Error(), "key length must be an greater than zero")
 
-	resultOne, _ := generateKeyData("", 6)
-	resultTwo, _ := generateKeyData("", 6)
+	_, _, err = generateKeyData("foo-bar", 0, policy)
+	assert.Equal(t, err.Error(), "key data must only contain characters from the alphabet "+DefaultAlphabet)
+
+	resultOne, _, _ := generateKeyData("", 6, policy)
+	resultTwo, _, _ := generateKeyData("", 6, policy)
 	assert.Equal(t, len(resultOne), 6)
 	assert.NotEqual(t, resultOne, resultTwo)
+
+	policy.Alphabet = "ab"
+	result, entropy, _ := generateKeyData("", 64, policy)
+	assert.Equal(t, len(result), 64)
+	assert.Equal(t, entropy, float64(64))
+	for _, c := range result {
+		assert.True(t, c == 'a' || c == 'b')
+	}
+}
+
+func TestGenerateKeyDataMinEntropy(t *testing.T) {
+	policy := DefaultPolicy()
+
+	_, _, err := generateKeyData("", 16, policy)
+	assert.Equal(t, err.Error(), "key length 16 provides 95.3 bits of entropy - at least 128.0 bits are required")
+
+	_, entropy, err := generateKeyData("", 32, policy)
+	assert.Nil(t, err)
+	assert.InDelta(t, entropy, 190.5, 0.1)
+
+	_, _, err = generateKeyData("000000000000000000000000000000", 0, policy)
+	assert.Equal(t, err.Error(), "key data has an estimated entropy of 10.0 bits - at least 128.0 bits are required")
+
+	result, _, err := generateKeyData("q7Xk2mZ9vR4tLp8wB3nY6hJ1cF5gD0sA", 0, policy)
+	assert.Nil(t, err)
+	assert.Equal(t, result, []byte("q7Xk2mZ9vR4tLp8wB3nY6hJ1cF5gD0sA"))
+}
+
+func TestPolicy(t *testing.T) {
+	assert.Nil(t, DefaultPolicy().Validate())
+	assert.Equal(t, Policy{Alphabet: "a"}.Validate().Error(), "key alphabet must contain at least two characters")
+	assert.Equal(t, Policy{Alphabet: "aba"}.Validate().Error(), "key alphabet contains the character 'a' more than once")
+	assert.Equal(t, Policy{Alphabet: "a b"}.Validate().Error(), "key alphabet may only contain printable, non whitespace ascii characters")
+	assert.Equal(t, Policy{Alphabet: "ab", MinEntropy: -1}.Validate().Error(), "minimum key entropy must be non negative")
+
+	assert.Equal(t, DefaultPolicy().EstimateEntropy([]byte("11111")), 3*math.Log2(10))
+	assert.Equal(t, DefaultPolicy().EstimateEntropy([]byte("a1")), 2*math.Log2(36))
+	assert.Equal(t, DefaultPolicy().EstimateEntropy([]byte("q7Xk")), 4*math.Log2(62))
+	assert.Equal(t, DefaultPolicy().EstimateEntropy([]byte("q7Xk2mZ9vR4tLp8wB3nY6hJ1cF5gD0sA")), 32*math.Log2(62))
+	assert.Equal(t, DefaultPolicy().Entropy(10), 10*math.Log2(62))
+
+	// repeated and sequential characters are not credited
+	assert.True(t, DefaultPolicy().EstimateEntropy([]byte(strings.Repeat("a", 32))) < DefaultMinEntropy)
+	assert.True(t, DefaultPolicy().EstimateEntropy([]byte(strings.Repeat("abc", 11))) < DefaultMinEntropy)
+	assert.True(t, DefaultPolicy().EstimateEntropy([]byte("abcdefghijklmnopqrstuvwxyzABCDEF")) < DefaultMinEntropy)
+	assert.True(t, DefaultPolicy().EstimateEntropy([]byte(strings.Repeat("abc", 100))) < DefaultMinEntropy)
+	_, err := checkKeyData(strings.Repeat("a", 32), 0, DefaultPolicy())
+	assert.NotNil(t, err)
+
+	// random key data is accepted
+	_, err = checkKeyData("q7Xk2mZ9vR4tLp8wB3nY6h", 0, DefaultPolicy())
+	assert.Nil(t, err)
+	_, err = checkKeyData("9f86d081884c7d659a2feaa0c55ad015", 0, DefaultPolicy())
+	assert.Nil(t, err)
+}
+
+func TestGeneratedKeyDataIsAccepted(t *testing.T) {
+	policy := DefaultPolicy()
+
+	for i := 0; i < 1000; i++ {
+		result, _, err := generateKeyData("", 22, policy)
+		assert.Nil(t, err)
+		_, err = checkKeyData(string(result), 0, policy)
+		assert.Nil(t, err)
+	}
+
+	for i := 0; i < 1000; i++ {
+		b := make([]byte, 16)
+		_, err := rand.Read(b)
+		assert.Nil(t, err)
+		_, err = checkKeyData(hex.EncodeToString(b), 0, policy)
+		assert.Nil(t, err)
+	}
+
+	policy.Alphabet = "ab"
+	_, _, err := generateKeyData("", 128, policy)
+	assert.Equal(t, err.Error(), "could not generate key data of length 128 with an estimated entropy of at least 128.0 bits - increase the key length")
 }
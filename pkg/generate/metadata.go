@@ -0,0 +1,105 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package generate
+
+import (
+	"fmt"
+	"regexp"
+)
+
+const (
+	qualifiedNameRegex = "^([a-z0-9]([-a-z0-9]*[a-z0-9])?(\\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*/)?[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$"
+	labelValueRegex    = "^(([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9])?$"
+	maxNameLength      = 63
+)
+
+// Metadata is the Kubernetes metadata given to a generated API key.
+type Metadata struct {
+	Namespace   string
+	Labels      map[string]string
+	Annotations map[string]string
+}
+
+// Validate ensures that the metadata will be accepted by Kubernetes.
+func (m Metadata) Validate() error {
+	if !regexp.MustCompile(keyNameRegex).MatchString(m.Namespace) {
+		return fmt.Errorf("namespace %q must conform to the pattern %s", m.Namespace, keyNameRegex)
+	}
+
+	for k, v := range m.Labels {
+		if err := validateQualifiedName(k); err != nil {
+			return fmt.Errorf("label key %s", err.Error())
+		}
+		if len(v) > maxNameLength || !regexp.MustCompile(labelValueRegex).MatchString(v) {
+			return fmt.Errorf("label value %q must be no more than %d characters and conform to the pattern %s", v, maxNameLength, labelValueRegex)
+		}
+	}
+
+	for k := range m.Annotations {
+		if err := validateQualifiedName(k); err != nil {
+			return fmt.Errorf("annotation key %s", err.Error())
+		}
+	}
+
+	return nil
+}
+
+// Merge returns a copy of the metadata with the namespace, labels and
+// annotations of other taking precedence over its own.
+func (m Metadata) Merge(other Metadata) Metadata {
+	merged := Metadata{
+		Namespace:   m.Namespace,
+		Labels:      mergeMaps(m.Labels, other.Labels),
+		Annotations: mergeMaps(m.Annotations, other.Annotations),
+	}
+	if len(other.Namespace) > 0 {
+		merged.Namespace = other.Namespace
+	}
+	return merged
+}
+
+func validateQualifiedName(name string) error {
+	n := name
+	for i := len(name) - 1; i >= 0; i-- {
+		if name[i] == '/' {
+			n = name[i+1:]
+			break
+		}
+	}
+	if len(n) > maxNameLength || !regexp.MustCompile(qualifiedNameRegex).MatchString(name) {
+		return fmt.Errorf("%q must have a name of no more than %d characters and conform to the pattern %s", name, maxNameLength, qualifiedNameRegex)
+	}
+	return nil
+}
+
+func mergeMaps(base, overrides map[string]string) map[string]string {
+	if len(base) < 1 && len(overrides) < 1 {
+		return nil
+	}
+	merged := make(map[string]string, len(base)+len(overrides))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range overrides {
+		merged[k] = v
+	}
+	return merged
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package generate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetadataValidate(t *testing.T) {
+	assert.Nil(t, Metadata{Namespace: "payments"}.Validate())
+	assert.Nil(t, Metadata{
+		Namespace:   "payments",
+		Labels:      map[string]string{"team": "a", "example.com/env": "dev", "empty": ""},
+		Annotations: map[string]string{"example.com/contact": "frank@example.com, bob@example.com"},
+	}.Validate())
+
+	assert.Equal(t, Metadata{Namespace: ""}.Validate().Error(), `namespace "" must conform to the pattern ^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+	assert.Equal(t, Metadata{Namespace: "Payments"}.Validate().Error(), `namespace "Payments" must conform to the pattern ^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+	assert.Contains(t, Metadata{Namespace: "payments", Labels: map[string]string{"team!": "a"}}.Validate().Error(), `label key "team!" must have a name`)
+	assert.Contains(t, Metadata{Namespace: "payments", Labels: map[string]string{"team": "a b"}}.Validate().Error(), `label value "a b" must be no more than 63 characters`)
+	assert.Contains(t, Metadata{Namespace: "payments", Annotations: map[string]string{"/owner": "frank"}}.Validate().Error(), `annotation key "/owner" must have a name`)
+}
+
+func TestMetadataMerge(t *testing.T) {
+	defaults := Metadata{
+		Namespace: "default",
+		Labels:    map[string]string{"team": "a", "env": "dev"},
+	}
+
+	assert.Equal(t, defaults.Merge(Metadata{}), defaults)
+	assert.Equal(t, defaults.Merge(Metadata{Namespace: "payments", Labels: map[string]string{"team": "b"}, Annotations: map[string]string{"owner": "frank"}}), Metadata{
+		Namespace:   "payments",
+		Labels:      map[string]string{"team": "b", "env": "dev"},
+		Annotations: map[string]string{"owner": "frank"},
+	})
+	assert.Equal(t, defaults.Labels, map[string]string{"team": "a", "env": "dev"})
+}
@@ -0,0 +1,182 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package generate
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/northwesternmutual/kanali/spec"
+	"github.com/northwesternmutual/kanalictl/pkg/manifest"
+	"github.com/northwesternmutual/kanalictl/utils"
+)
+
+// Stdout is the out file name that writes the Kubernetes config to standard output.
+const Stdout = "-"
+
+// Overwrite controls what happens when the out file already exists.
+type Overwrite string
+
+const (
+	// OverwritePrompt asks whether to overwrite the out file. If standard
+	// input is not a terminal, the out file is not overwritten.
+	OverwritePrompt Overwrite = "prompt"
+	// OverwriteForce always overwrites the out file.
+	OverwriteForce Overwrite = "force"
+	// OverwriteNever never overwrites the out file.
+	OverwriteNever Overwrite = "never"
+	// OverwriteAppend adds the Kubernetes config as another document in the out file.
+	OverwriteAppend Overwrite = "append"
+)
+
+// ParseOverwrite parses an overwrite mode.
+func ParseOverwrite(mode string) (Overwrite, error) {
+	switch Overwrite(mode) {
+	case OverwritePrompt, OverwriteForce, OverwriteNever, OverwriteAppend:
+		return Overwrite(mode), nil
+	default:
+		return "", fmt.Errorf("overwrite must be one of %s, %s, %s or %s", OverwritePrompt, OverwriteForce, OverwriteNever, OverwriteAppend)
+	}
+}
+
+// CheckOutFile determines how the Kubernetes config will be written to the out
+// file. It should be called before a key is generated so that a key is never
+// generated for a config that cannot be written. It returns either
+// OverwriteForce, if the out file should be (over)written, or OverwriteAppend.
+func CheckOutFile(outFileName string, mode Overwrite, name, namespace string) (Overwrite, error) {
+	if len(outFileName) < 1 || outFileName == Stdout {
+		return OverwriteForce, nil
+	}
+
+	if mode == OverwriteAppend {
+		if filepath.Ext(outFileName) == ".json" {
+			return "", errors.New("can only append to a yaml out file")
+		}
+		if err := checkNotInFile(outFileName, name, namespace); err != nil {
+			return "", err
+		}
+		return OverwriteAppend, nil
+	}
+
+	_, err := os.Stat(outFileName)
+	if os.IsNotExist(err) {
+		return OverwriteForce, nil
+	} else if err != nil {
+		return "", err
+	}
+
+	switch mode {
+	case OverwriteForce:
+		return OverwriteForce, nil
+	case OverwriteNever:
+		return "", fmt.Errorf("%s already exists - not overwriting it", outFileName)
+	}
+
+	if !utils.IsTerminal(os.Stdin) {
+		return "", fmt.Errorf("%s already exists - use --overwrite=%s, %s or %s", outFileName, OverwriteForce, OverwriteNever, OverwriteAppend)
+	}
+
+	ok, err := confirm(os.Stdin, fmt.Sprintf("%s exists - do you want to override it?", outFileName))
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("%s already exists - not overwriting it", outFileName)
+	}
+
+	return OverwriteForce, nil
+}
+
+func confirm(in io.Reader, question string) (bool, error) {
+	reader := bufio.NewReader(in)
+
+	for {
+		fmt.Printf("%s (Y/n) ", question)
+		input, err := reader.ReadString('\n')
+		switch strings.TrimSpace(input) {
+		case "Y":
+			return true, nil
+		case "n":
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+}
+
+func checkNotInFile(outFileName, name, namespace string) error {
+	f, err := manifest.Read(outFileName)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for _, doc := range f.Documents {
+		if doc.Kind() != "ApiKey" {
+			continue
+		}
+		var existing spec.APIKey
+		if err := yaml.Unmarshal(doc.Data, &existing); err != nil {
+			continue
+		}
+		if existing.ObjectMeta.Name == name && existing.ObjectMeta.Namespace == namespace {
+			return fmt.Errorf("%s already contains ApiKey %s in namespace %s", outFileName, name, namespace)
+		}
+	}
+
+	return nil
+}
+
+func appendTo(outFileName string, keyCRD spec.APIKey) error {
+	yamlData, err := yaml.Marshal(keyCRD)
+	if err != nil {
+		return err
+	}
+
+	f, err := manifest.Read(outFileName)
+	if os.IsNotExist(err) {
+		return ioutil.WriteFile(outFileName, yamlData, 0644)
+	} else if err != nil {
+		return err
+	}
+
+	// reuse a trailing empty document rather than adding another separator
+	last := f.Documents[len(f.Documents)-1]
+	if last.Empty() {
+		if len(last.Data) > 0 && last.Data[len(last.Data)-1] != '\n' {
+			last.Data = append(last.Data, '\n')
+		}
+		last.Data = append(last.Data, yamlData...)
+	} else {
+		f.Insert(last.Index, yamlData)
+	}
+
+	return f.Write()
+}
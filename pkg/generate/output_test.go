@@ -0,0 +1,109 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package generate
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/northwesternmutual/kanalictl/pkg/manifest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOverwrite(t *testing.T) {
+	mode, err := ParseOverwrite("append")
+	assert.Nil(t, err)
+	assert.Equal(t, mode, OverwriteAppend)
+
+	_, err = ParseOverwrite("sometimes")
+	assert.Equal(t, err.Error(), "overwrite must be one of prompt, force, never or append")
+}
+
+func TestCheckOutFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kanalictl")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	outFile := filepath.Join(dir, "key.yaml")
+
+	mode, err := CheckOutFile(outFile, OverwriteNever, "frank", "default")
+	assert.Nil(t, err)
+	assert.Equal(t, mode, OverwriteForce)
+
+	mode, err = CheckOutFile(Stdout, OverwriteNever, "frank", "default")
+	assert.Nil(t, err)
+	assert.Equal(t, mode, OverwriteForce)
+
+	assert.Nil(t, Write(outFile, OverwriteForce, CRD("frank", Metadata{Namespace: "default"}, []byte("abc"))))
+
+	_, err = CheckOutFile(outFile, OverwriteNever, "frank", "default")
+	assert.Equal(t, err.Error(), outFile+" already exists - not overwriting it")
+
+	// standard input is not a terminal while testing
+	_, err = CheckOutFile(outFile, OverwritePrompt, "frank", "default")
+	assert.Equal(t, err.Error(), outFile+" already exists - use --overwrite=force, never or append")
+
+	mode, err = CheckOutFile(outFile, OverwriteForce, "frank", "default")
+	assert.Nil(t, err)
+	assert.Equal(t, mode, OverwriteForce)
+
+	_, err = CheckOutFile(outFile, OverwriteAppend, "frank", "default")
+	assert.Equal(t, err.Error(), outFile+" already contains ApiKey frank in namespace default")
+
+	_, err = CheckOutFile(filepath.Join(dir, "key.json"), OverwriteAppend, "frank", "default")
+	assert.Equal(t, err.Error(), "can only append to a yaml out file")
+
+	mode, err = CheckOutFile(outFile, OverwriteAppend, "bob", "default")
+	assert.Nil(t, err)
+	assert.Equal(t, mode, OverwriteAppend)
+
+	assert.Nil(t, Write(outFile, OverwriteAppend, CRD("bob", Metadata{Namespace: "default"}, []byte("abc"))))
+	assert.Nil(t, ioutil.WriteFile(outFile, append(mustReadFile(t, outFile), []byte("---\n")...), 0644))
+	assert.Nil(t, Write(outFile, OverwriteAppend, CRD("alice", Metadata{Namespace: "default"}, []byte("abc"))))
+
+	f, err := manifest.Read(outFile)
+	assert.Nil(t, err)
+	assert.Equal(t, len(f.Documents), 3)
+	assert.Contains(t, string(f.Documents[1].Data), "name: bob")
+	assert.Contains(t, string(f.Documents[2].Data), "name: alice")
+}
+
+func TestConfirm(t *testing.T) {
+	ok, err := confirm(bytes.NewBufferString("maybe\nY\n"), "overwrite?")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	ok, err = confirm(bytes.NewBufferString("n"), "overwrite?")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	_, err = confirm(bytes.NewBufferString(""), "overwrite?")
+	assert.NotNil(t, err)
+}
+
+func mustReadFile(t *testing.T, file string) []byte {
+	data, err := ioutil.ReadFile(file)
+	assert.Nil(t, err)
+	return data
+}
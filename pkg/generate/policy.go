@@ -0,0 +1,155 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package generate
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+const (
+	// DefaultAlphabet is the set of characters generated API keys are drawn from.
+	DefaultAlphabet = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	// DefaultMinEntropy is the minimum number of bits of entropy an API key must have.
+	DefaultMinEntropy = 128
+)
+
+// Policy controls the alphabet API key data is drawn from as well
+// as the minimum entropy, in bits, that API key data must provide.
+type Policy struct {
+	Alphabet   string
+	MinEntropy float64
+}
+
+// DefaultPolicy returns the policy used when none is configured.
+func DefaultPolicy() Policy {
+	return Policy{
+		Alphabet:   DefaultAlphabet,
+		MinEntropy: DefaultMinEntropy,
+	}
+}
+
+// Validate ensures that a policy can be used to generate API key data.
+func (p Policy) Validate() error {
+	if len(p.Alphabet) < 2 {
+		return errors.New("key alphabet must contain at least two characters")
+	}
+
+	seen := map[byte]bool{}
+	for i := 0; i < len(p.Alphabet); i++ {
+		c := p.Alphabet[i]
+		if c <= ' ' || c > '~' {
+			return errors.New("key alphabet may only contain printable, non whitespace ascii characters")
+		}
+		if seen[c] {
+			return fmt.Errorf("key alphabet contains the character %q more than once", c)
+		}
+		seen[c] = true
+	}
+
+	if p.MinEntropy < 0 {
+		return errors.New("minimum key entropy must be non negative")
+	}
+
+	return nil
+}
+
+// Entropy returns the number of bits of entropy in API key data of
+// the given length drawn uniformly at random from the policy alphabet.
+func (p Policy) Entropy(length int) float64 {
+	return entropy(length, len(p.Alphabet))
+}
+
+// EstimateEntropy returns an estimate of the number of bits of entropy in
+// API key data that was not generated by this package. Because the data
+// may not have been chosen at random, the estimate only credits each
+// character with the size of the character classes (digits, lower case,
+// upper case and symbols) that actually appear in the data, and gives no
+// credit to a character that continues a run of repeated or sequential
+// characters or that repeats a sequence of characters seen earlier, so that
+// data such as "aaaa..." or "abcabc..." is not mistaken for a strong key.
+func (p Policy) EstimateEntropy(keyData []byte) float64 {
+	classes := map[string]int{}
+	for i := 0; i < len(p.Alphabet); i++ {
+		classes[characterClass(p.Alphabet[i])]++
+	}
+
+	used := map[string]bool{}
+	seen := map[string]bool{}
+	credited := 0
+	for i, c := range keyData {
+		used[characterClass(c)] = true
+		if i < 3 {
+			credited++
+			continue
+		}
+		sequence := string(keyData[i-2 : i+1])
+		run := patterned(keyData[i-3], keyData[i-2]) && patterned(keyData[i-2], keyData[i-1]) && patterned(keyData[i-1], c)
+		if !run && !seen[sequence] {
+			credited++
+		}
+		seen[sequence] = true
+	}
+
+	poolSize := 0
+	for class := range used {
+		poolSize += classes[class]
+	}
+
+	return entropy(credited, poolSize)
+}
+
+func (p Policy) allows(keyData string) bool {
+	for _, c := range keyData {
+		if !strings.ContainsRune(p.Alphabet, c) {
+			return false
+		}
+	}
+	return true
+}
+
+func entropy(length, poolSize int) float64 {
+	if length < 1 || poolSize < 2 {
+		return 0
+	}
+	return float64(length) * math.Log2(float64(poolSize))
+}
+
+// patterned reports whether a character repeats the one before it or
+// follows it in sequence, in either direction.
+func patterned(prev, c byte) bool {
+	return c == prev || c == prev+1 || c+1 == prev
+}
+
+func characterClass(c byte) string {
+	switch {
+	case c >= '0' && c <= '9':
+		return "digit"
+	case c >= 'a' && c <= 'z':
+		return "lower"
+	case c >= 'A' && c <= 'Z':
+		return "upper"
+	default:
+		return "symbol"
+	}
+}
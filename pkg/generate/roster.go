@@ -0,0 +1,345 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package generate
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/northwesternmutual/kanali/spec"
+)
+
+// RosterEntry describes a single API key that should be generated in bulk.
+type RosterEntry struct {
+	Name      string            `json:"name"`
+	Namespace string            `json:"namespace,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Data      string            `json:"data,omitempty"`
+
+	// position describes where in the roster file this entry was found
+	position string
+}
+
+// GeneratedKey is an API key that was generated from a roster entry.
+type GeneratedKey struct {
+	CRD                spec.APIKey
+	UnencryptedKeyData []byte
+	Entropy            float64
+}
+
+// ReadRoster reads a list of API keys to generate from either
+// a CSV or YAML file. A CSV roster must have a header row naming
+// its columns, which may be name, namespace, labels and data. Labels
+// are given as a semicolon separated list of key=value pairs.
+func ReadRoster(path string) ([]RosterEntry, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch filepath.Ext(path) {
+	case ".csv":
+		return parseCSVRoster(data)
+	case ".yaml", ".yml", ".json":
+		return parseYAMLRoster(data)
+	default:
+		return nil, errors.New("roster file must be either csv, json or yaml format")
+	}
+}
+
+// ValidateRoster ensures that an API key can be generated for every entry
+// in the roster. Every entry is checked so that all problems are reported
+// at once, before any key is generated.
+func ValidateRoster(entries []RosterEntry, defaults Metadata, length int, policy Policy) error {
+	if len(entries) < 1 {
+		return errors.New("roster does not contain any api keys")
+	}
+
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	nameRegex := regexp.MustCompile(keyNameRegex)
+	seen := map[string]string{}
+	problems := []string{}
+
+	for _, entry := range entries {
+		meta := entry.metadata(defaults)
+		if !nameRegex.MatchString(entry.Name) {
+			problems = append(problems, fmt.Sprintf("%s: key name %q must conform to the pattern %s", entry.position, entry.Name, keyNameRegex))
+		}
+		if err := meta.Validate(); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", entry.position, err.Error()))
+		}
+		if _, err := checkKeyData(entry.Data, length, policy); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", entry.position, err.Error()))
+		}
+
+		id := meta.Namespace + "/" + entry.Name
+		if previous, ok := seen[id]; ok {
+			problems = append(problems, fmt.Sprintf("%s: api key %s is already defined at %s", entry.position, id, previous))
+		}
+		seen[id] = entry.position
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("roster is invalid - no api keys were generated:\n%s", strings.Join(problems, "\n"))
+	}
+
+	return nil
+}
+
+// KeysFromRoster generates an API key for every entry in a roster. The
+// namespace and labels of each entry take precedence over the defaults.
+func KeysFromRoster(entries []RosterEntry, defaults Metadata, length int, policy Policy, encryptKey *rsa.PublicKey) ([]GeneratedKey, error) {
+	keys := make([]GeneratedKey, 0, len(entries))
+
+	for _, entry := range entries {
+		unencryptedKeyData, encryptedKeyData, entropy, err := Key(entry.Name, entry.Data, length, policy, encryptKey)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", entry.position, err.Error())
+		}
+
+		keys = append(keys, GeneratedKey{
+			CRD:                CRD(entry.Name, entry.metadata(defaults), encryptedKeyData),
+			UnencryptedKeyData: unencryptedKeyData,
+			Entropy:            entropy,
+		})
+	}
+
+	return keys, nil
+}
+
+// CheckRosterOutput ensures that the Kubernetes configs and unencrypted
+// API keys for a roster can be written. Existing Kubernetes configs are
+// only overwritten when the overwrite mode is OverwriteForce and an
+// existing delivery file is never overwritten.
+func CheckRosterOutput(out, deliveryFile string, entries []RosterEntry, defaults Metadata, overwrite Overwrite) error {
+	if len(out) < 1 {
+		return errors.New("out file or directory must be specified when generating multiple api keys")
+	}
+	if len(deliveryFile) < 1 {
+		return errors.New("delivery file must be specified when generating multiple api keys")
+	}
+
+	if _, err := deliveryFormat(deliveryFile); err != nil {
+		return err
+	}
+
+	if overwrite == OverwriteAppend {
+		return fmt.Errorf("overwrite mode %s is not supported when generating multiple api keys", OverwriteAppend)
+	}
+
+	files := []string{deliveryFile}
+	for _, entry := range entries {
+		if overwrite == OverwriteForce {
+			break
+		}
+		file, err := rosterOutFile(out, entry.metadata(defaults).Namespace, entry.Name)
+		if err != nil {
+			return err
+		}
+		files = append(files, file)
+	}
+
+	for _, file := range files {
+		if _, err := os.Stat(file); err == nil {
+			return fmt.Errorf("%s already exists - no api keys were generated", file)
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteAll writes the Kubernetes configs for many API keys. If out is a
+// directory, each key is written to its own file in a subdirectory named
+// after its namespace. Otherwise every key is written to out as a multi
+// document YAML file.
+func WriteAll(out string, keys []GeneratedKey) error {
+	files := []string{}
+	docs := map[string][][]byte{}
+
+	for _, key := range keys {
+		file, err := rosterOutFile(out, key.CRD.ObjectMeta.Namespace, key.CRD.ObjectMeta.Name)
+		if err != nil {
+			return err
+		}
+
+		yamlData, err := yaml.Marshal(key.CRD)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := docs[file]; !ok {
+			files = append(files, file)
+		}
+		docs[file] = append(docs[file], yamlData)
+	}
+
+	for _, file := range files {
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return err
+		}
+
+		if err := ioutil.WriteFile(file, bytes.Join(docs[file], []byte("---\n")), 0644); err != nil {
+			return err
+		}
+
+		fmt.Printf("Corresponding Kubernetes config written to %s\n", file)
+	}
+
+	return nil
+}
+
+func parseCSVRoster(data []byte) ([]RosterEntry, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("roster does not contain any api keys")
+	} else if err != nil {
+		return nil, err
+	}
+
+	columns := map[string]int{}
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(column))
+		switch column {
+		case "name", "namespace", "labels", "data":
+			columns[column] = i
+		default:
+			return nil, fmt.Errorf("unknown roster column %q - valid columns are name, namespace, labels and data", column)
+		}
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, errors.New("roster must contain a name column")
+	}
+
+	entries := []RosterEntry{}
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		entry := RosterEntry{
+			position: fmt.Sprintf("row %d", row),
+		}
+
+		for column, i := range columns {
+			value := strings.TrimSpace(record[i])
+			switch column {
+			case "name":
+				entry.Name = value
+			case "namespace":
+				entry.Namespace = value
+			case "data":
+				entry.Data = value
+			case "labels":
+				labels, err := parseLabels(value)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %s", entry.position, err.Error())
+				}
+				entry.Labels = labels
+			}
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func parseYAMLRoster(data []byte) ([]RosterEntry, error) {
+	entries := []RosterEntry{}
+	if err := yaml.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("roster must be a list of api keys: %s", err.Error())
+	}
+
+	for i := range entries {
+		entries[i].position = fmt.Sprintf("entry %d", i+1)
+	}
+
+	return entries, nil
+}
+
+func parseLabels(value string) (map[string]string, error) {
+	if len(value) < 1 {
+		return nil, nil
+	}
+
+	labels := map[string]string{}
+	for _, pair := range strings.Split(value, ";") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("label %q must be of the form key=value", pair)
+		}
+		labels[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+
+	return labels, nil
+}
+
+func rosterOutFile(out, namespace, name string) (string, error) {
+	if isDir(out) {
+		return filepath.Join(out, namespace, name+".yaml"), nil
+	}
+
+	switch filepath.Ext(out) {
+	case ".yaml", ".yml":
+		return out, nil
+	case "":
+		return out + ".yaml", nil
+	default:
+		return "", errors.New("out file must be yaml format when generating multiple api keys")
+	}
+}
+
+func (e RosterEntry) metadata(defaults Metadata) Metadata {
+	return defaults.Merge(Metadata{
+		Namespace: e.Namespace,
+		Labels:    e.Labels,
+	})
+}
+
+func isDir(path string) bool {
+	if strings.HasSuffix(path, string(os.PathSeparator)) {
+		return true
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
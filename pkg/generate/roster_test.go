@@ -0,0 +1,142 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package generate
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/northwesternmutual/kanalictl/pkg/cluster"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCSVRoster(t *testing.T) {
+	entries, err := parseCSVRoster([]byte("name,namespace,labels\n# comment\nfoo,,team=a;env=dev\nbar,payments,\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, len(entries), 2)
+	assert.Equal(t, entries[0].Name, "foo")
+	assert.Equal(t, entries[0].Namespace, "")
+	assert.Equal(t, entries[0].Labels, map[string]string{"team": "a", "env": "dev"})
+	assert.Equal(t, entries[0].position, "row 1")
+	assert.Equal(t, entries[1].Namespace, "payments")
+	assert.Nil(t, entries[1].Labels)
+
+	_, err = parseCSVRoster([]byte("name,owner\nfoo,bar\n"))
+	assert.Equal(t, err.Error(), `unknown roster column "owner" - valid columns are name, namespace, labels and data`)
+
+	_, err = parseCSVRoster([]byte("namespace\nfoo\n"))
+	assert.Equal(t, err.Error(), "roster must contain a name column")
+
+	_, err = parseCSVRoster([]byte("name,labels\nfoo,team\n"))
+	assert.Equal(t, err.Error(), `row 1: label "team" must be of the form key=value`)
+}
+
+func TestParseYAMLRoster(t *testing.T) {
+	entries, err := parseYAMLRoster([]byte("- name: foo\n  labels:\n    team: a\n- name: bar\n  namespace: payments\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, len(entries), 2)
+	assert.Equal(t, entries[0].Labels, map[string]string{"team": "a"})
+	assert.Equal(t, entries[1].Namespace, "payments")
+	assert.Equal(t, entries[1].position, "entry 2")
+
+	_, err = parseYAMLRoster([]byte("name: foo\n"))
+	assert.NotNil(t, err)
+}
+
+func TestValidateRoster(t *testing.T) {
+	policy := DefaultPolicy()
+
+	assert.Equal(t, ValidateRoster(nil, Metadata{Namespace: cluster.DefaultNamespace}, 32, policy).Error(), "roster does not contain any api keys")
+
+	entries := []RosterEntry{
+		{Name: "foo", position: "row 1"},
+		{Name: "foo", Namespace: "default", position: "row 2"},
+		{Name: "Bar", position: "row 3"},
+		{Name: "baz", Data: "short", position: "row 4"},
+		{Name: "foo", Namespace: "payments", position: "row 5"},
+	}
+	assert.Equal(t, ValidateRoster(entries, Metadata{Namespace: cluster.DefaultNamespace}, 32, policy).Error(), `roster is invalid - no api keys were generated:
+row 2: api key default/foo is already defined at row 1
+row 3: key name "Bar" must conform to the pattern ^[a-z0-9]([-a-z0-9]*[a-z0-9])?$
+row 4: key data has an estimated entropy of 23.5 bits - at least 128.0 bits are required`)
+
+	assert.Nil(t, ValidateRoster(entries[4:], Metadata{Namespace: cluster.DefaultNamespace}, 32, policy))
+	assert.NotNil(t, ValidateRoster(entries[4:], Metadata{Namespace: cluster.DefaultNamespace}, 8, policy))
+}
+
+func TestKeysFromRoster(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	dir, err := ioutil.TempDir("", "kanalictl")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	entries := []RosterEntry{
+		{Name: "foo", Labels: map[string]string{"team": "a"}},
+		{Name: "foo", Namespace: "payments"},
+	}
+	defaults := Metadata{
+		Namespace:   cluster.DefaultNamespace,
+		Labels:      map[string]string{"team": "b", "env": "dev"},
+		Annotations: map[string]string{"example.com/owner": "frank"},
+	}
+
+	keys, err := KeysFromRoster(entries, defaults, 32, DefaultPolicy(), &privateKey.PublicKey)
+	assert.Nil(t, err)
+	assert.Equal(t, len(keys), 2)
+	assert.Equal(t, keys[0].CRD.ObjectMeta.Namespace, "default")
+	assert.Equal(t, keys[0].CRD.ObjectMeta.Labels, map[string]string{"team": "a", "env": "dev"})
+	assert.Equal(t, keys[0].CRD.ObjectMeta.Annotations, map[string]string{"example.com/owner": "frank"})
+	assert.Equal(t, keys[1].CRD.ObjectMeta.Namespace, "payments")
+	assert.Equal(t, keys[1].CRD.ObjectMeta.Labels, map[string]string{"team": "b", "env": "dev"})
+	assert.NotEqual(t, keys[0].UnencryptedKeyData, keys[1].UnencryptedKeyData)
+
+	outDir := filepath.Join(dir, "keys") + string(os.PathSeparator)
+	deliveryFile := filepath.Join(dir, "delivery.csv")
+	assert.Nil(t, CheckRosterOutput(outDir, deliveryFile, entries, defaults, OverwritePrompt))
+	assert.Nil(t, WriteAll(outDir, keys))
+	assert.Nil(t, WriteDelivery(deliveryFile, keys, nil))
+
+	_, err = os.Stat(filepath.Join(dir, "keys", "default", "foo.yaml"))
+	assert.Nil(t, err)
+	_, err = os.Stat(filepath.Join(dir, "keys", "payments", "foo.yaml"))
+	assert.Nil(t, err)
+
+	info, err := os.Stat(deliveryFile)
+	assert.Nil(t, err)
+	assert.Equal(t, info.Mode().Perm(), os.FileMode(0600))
+	assert.NotNil(t, WriteDelivery(deliveryFile, keys, nil))
+	assert.Equal(t, CheckRosterOutput(outDir, deliveryFile, entries, defaults, OverwritePrompt).Error(), deliveryFile+" already exists - no api keys were generated")
+
+	assert.Equal(t, CheckRosterOutput(outDir, filepath.Join(dir, "other.csv"), entries, defaults, OverwritePrompt).Error(), filepath.Join(outDir, "default", "foo.yaml")+" already exists - no api keys were generated")
+	assert.Nil(t, CheckRosterOutput(outDir, filepath.Join(dir, "other.csv"), entries, defaults, OverwriteForce))
+
+	outFile := filepath.Join(dir, "keys.yaml")
+	assert.Nil(t, WriteAll(outFile, keys))
+	data, err := ioutil.ReadFile(outFile)
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), "---\n")
+}
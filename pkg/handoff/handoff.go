@@ -0,0 +1,176 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package handoff
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"golang.org/x/crypto/openpgp"
+	pgpArmor "golang.org/x/crypto/openpgp/armor"
+	// keys without hash preferences fall back to RIPEMD160, which
+	// openpgp refuses to encrypt to unless it is registered
+	_ "golang.org/x/crypto/ripemd160"
+)
+
+// Recipients are the consumers an unencrypted API key is handed off to.
+// An unencrypted API key is encrypted so that only they can read it.
+type Recipients struct {
+	age []age.Recipient
+	pgp openpgp.EntityList
+}
+
+// LoadRecipients loads age and OpenPGP recipients. Each age recipient is
+// either a public key such as age1... or a file containing one public key
+// per line. Each OpenPGP recipient is a file containing an armored or binary
+// public key. Recipients of both kinds cannot be used at the same time.
+func LoadRecipients(ageRecipients, pgpKeyFiles []string) (*Recipients, error) {
+	if len(ageRecipients) > 0 && len(pgpKeyFiles) > 0 {
+		return nil, errors.New("age and OpenPGP recipients cannot be used at the same time")
+	}
+
+	r := &Recipients{}
+
+	for _, recipient := range ageRecipients {
+		parsed, err := loadAgeRecipients(recipient)
+		if err != nil {
+			return nil, err
+		}
+		r.age = append(r.age, parsed...)
+	}
+
+	for _, file := range pgpKeyFiles {
+		entities, err := loadPGPKeys(file)
+		if err != nil {
+			return nil, err
+		}
+		r.pgp = append(r.pgp, entities...)
+	}
+
+	return r, nil
+}
+
+// Len returns the number of recipients.
+func (r *Recipients) Len() int {
+	if r == nil {
+		return 0
+	}
+	return len(r.age) + len(r.pgp)
+}
+
+// Encrypt encrypts data for every recipient and returns it ASCII armored.
+func (r *Recipients) Encrypt(data []byte) ([]byte, error) {
+	if r.Len() < 1 {
+		return nil, errors.New("no recipients provided")
+	}
+
+	buf := &bytes.Buffer{}
+
+	if len(r.age) > 0 {
+		a := armor.NewWriter(buf)
+		w, err := age.Encrypt(a, r.age...)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeAndClose(w, data); err != nil {
+			return nil, err
+		}
+		if err := a.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	a, err := pgpArmor.Encode(buf, "PGP MESSAGE", nil)
+	if err != nil {
+		return nil, err
+	}
+	w, err := openpgp.Encrypt(a, r.pgp, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeAndClose(w, data); err != nil {
+		return nil, err
+	}
+	if err := a.Close(); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+
+	return buf.Bytes(), nil
+}
+
+func loadAgeRecipients(recipient string) ([]age.Recipient, error) {
+	if strings.HasPrefix(recipient, "age1") {
+		parsed, err := age.ParseX25519Recipient(recipient)
+		if err != nil {
+			return nil, fmt.Errorf("invalid age recipient %s: %s", recipient, err.Error())
+		}
+		return []age.Recipient{parsed}, nil
+	}
+
+	f, err := os.Open(recipient)
+	if err != nil {
+		return nil, fmt.Errorf("age recipient %s is neither a public key nor a readable file: %s", recipient, err.Error())
+	}
+	defer f.Close()
+
+	parsed, err := age.ParseRecipients(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid age recipients file %s: %s", recipient, err.Error())
+	}
+	return parsed, nil
+}
+
+func loadPGPKeys(file string) (openpgp.EntityList, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+	if err != nil {
+		entities, err = openpgp.ReadKeyRing(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid OpenPGP public key file %s: %s", file, err.Error())
+	}
+	if len(entities) < 1 {
+		return nil, fmt.Errorf("OpenPGP public key file %s does not contain any keys", file)
+	}
+
+	return entities, nil
+}
+
+func writeAndClose(w io.WriteCloser, data []byte) error {
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
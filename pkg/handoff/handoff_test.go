@@ -0,0 +1,121 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package handoff
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/openpgp"
+	pgpArmor "golang.org/x/crypto/openpgp/armor"
+)
+
+func TestLoadRecipients(t *testing.T) {
+	dir, err := ioutil.TempDir("", "handoff")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	identity, err := age.GenerateX25519Identity()
+	assert.Nil(t, err)
+	other, err := age.GenerateX25519Identity()
+	assert.Nil(t, err)
+
+	recipientsFile := filepath.Join(dir, "recipients.txt")
+	assert.Nil(t, ioutil.WriteFile(recipientsFile, []byte("# team b\n"+other.Recipient().String()+"\n"), 0644))
+
+	r, err := LoadRecipients([]string{identity.Recipient().String(), recipientsFile}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, r.Len(), 2)
+
+	_, err = LoadRecipients([]string{"age1notakey"}, nil)
+	assert.NotNil(t, err)
+	_, err = LoadRecipients([]string{filepath.Join(dir, "missing.txt")}, nil)
+	assert.NotNil(t, err)
+	_, err = LoadRecipients([]string{identity.Recipient().String()}, []string{recipientsFile})
+	assert.Equal(t, err.Error(), "age and OpenPGP recipients cannot be used at the same time")
+	_, err = LoadRecipients(nil, []string{recipientsFile})
+	assert.NotNil(t, err)
+
+	r, err = LoadRecipients(nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, r.Len(), 0)
+	_, err = r.Encrypt([]byte("secret"))
+	assert.Equal(t, err.Error(), "no recipients provided")
+}
+
+func TestEncryptAge(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	assert.Nil(t, err)
+
+	r, err := LoadRecipients([]string{identity.Recipient().String()}, nil)
+	assert.Nil(t, err)
+
+	ciphertext, err := r.Encrypt([]byte("secret"))
+	assert.Nil(t, err)
+	assert.True(t, bytes.HasPrefix(ciphertext, []byte(armor.Header)))
+
+	plaintext, err := age.Decrypt(armor.NewReader(bytes.NewReader(ciphertext)), identity)
+	assert.Nil(t, err)
+	data, err := ioutil.ReadAll(plaintext)
+	assert.Nil(t, err)
+	assert.Equal(t, string(data), "secret")
+}
+
+func TestEncryptPGP(t *testing.T) {
+	dir, err := ioutil.TempDir("", "handoff")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	entity, err := openpgp.NewEntity("team a", "", "team-a@example.com", nil)
+	assert.Nil(t, err)
+
+	buf := &bytes.Buffer{}
+	w, err := pgpArmor.Encode(buf, openpgp.PublicKeyType, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, entity.Serialize(w))
+	assert.Nil(t, w.Close())
+
+	keyFile := filepath.Join(dir, "team-a.asc")
+	assert.Nil(t, ioutil.WriteFile(keyFile, buf.Bytes(), 0644))
+
+	r, err := LoadRecipients(nil, []string{keyFile})
+	assert.Nil(t, err)
+	assert.Equal(t, r.Len(), 1)
+
+	ciphertext, err := r.Encrypt([]byte("secret"))
+	assert.Nil(t, err)
+
+	block, err := pgpArmor.Decode(bytes.NewReader(ciphertext))
+	assert.Nil(t, err)
+	assert.Equal(t, block.Type, "PGP MESSAGE")
+
+	md, err := openpgp.ReadMessage(block.Body, openpgp.EntityList{entity}, nil, nil)
+	assert.Nil(t, err)
+	data, err := ioutil.ReadAll(md.UnverifiedBody)
+	assert.Nil(t, err)
+	assert.Equal(t, string(data), "secret")
+}
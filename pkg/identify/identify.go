@@ -0,0 +1,184 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package identify
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"io"
+
+	"github.com/ghodss/yaml"
+	"github.com/northwesternmutual/kanali/spec"
+	"github.com/northwesternmutual/kanalictl/pkg/decrypt"
+	"github.com/northwesternmutual/kanalictl/pkg/manifest"
+	"github.com/olekukonko/tablewriter"
+)
+
+// Match is an API key resource whose data is the presented API key.
+type Match struct {
+	Name      string
+	Namespace string
+	Source    string
+	Document  int
+	Routes    []Route
+}
+
+// Route is an ApiKeyBinding that grants a matching API key access to an
+// ApiProxy. Path is empty if the ApiProxy could not be found.
+type Route struct {
+	Binding   string
+	Namespace string
+	Proxy     string
+	Path      string
+}
+
+// Resources are the ApiKeyBindings and ApiProxies an API key may reach.
+type Resources struct {
+	Bindings []spec.APIKeyBinding
+	Proxies  []spec.APIProxy
+}
+
+// Identify compares the presented API key against every decrypted result
+// and returns those that match along with the routes they can reach. Every
+// result is compared in constant time so that neither the position of a
+// match nor how much of a key matched can be learned from timing.
+func Identify(results []decrypt.Result, presented []byte, resources Resources) []Match {
+	want := sha256.Sum256(presented)
+
+	matches := []Match{}
+	for _, r := range results {
+		if r.Status != decrypt.StatusDecrypted {
+			continue
+		}
+		got := sha256.Sum256([]byte(r.Key))
+		if subtle.ConstantTimeCompare(want[:], got[:]) != 1 {
+			continue
+		}
+		matches = append(matches, Match{
+			Name:      r.Name,
+			Namespace: r.Namespace,
+			Source:    r.Source,
+			Document:  r.Document,
+			Routes:    resources.routes(r.Name),
+		})
+	}
+
+	return matches
+}
+
+// FromFiles returns every ApiKeyBinding and ApiProxy found under path.
+func FromFiles(path string) (Resources, error) {
+	resources := Resources{}
+
+	fileList, err := manifest.Discover(path)
+	if err != nil {
+		return resources, err
+	}
+
+	for _, file := range fileList {
+		f, err := manifest.Read(file)
+		if err != nil {
+			return resources, err
+		}
+		for _, doc := range f.Documents {
+			switch doc.Kind() {
+			case "ApiKeyBinding":
+				var binding spec.APIKeyBinding
+				if err := yaml.Unmarshal(doc.Data, &binding); err != nil {
+					return resources, fmt.Errorf("%s: %s", file, err.Error())
+				}
+				resources.Bindings = append(resources.Bindings, binding)
+			case "ApiProxy":
+				var proxy spec.APIProxy
+				if err := yaml.Unmarshal(doc.Data, &proxy); err != nil {
+					return resources, fmt.Errorf("%s: %s", file, err.Error())
+				}
+				resources.Proxies = append(resources.Proxies, proxy)
+			}
+		}
+	}
+
+	return resources, nil
+}
+
+// Render writes matches to w as a table. Each route a match can reach is
+// written as its own row.
+func Render(w io.Writer, matches []Match) {
+	if len(matches) < 1 {
+		fmt.Fprintln(w, "no API key matches the presented API key")
+		return
+	}
+
+	table := tablewriter.NewWriter(w)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"API Key Name", "Namespace", "ApiKeyBinding", "ApiProxy", "Path", "Source"})
+
+	for _, m := range matches {
+		source := fmt.Sprintf("%s#%d", m.Source, m.Document)
+		if len(m.Routes) < 1 {
+			table.Append([]string{m.Name, m.Namespace, "-", "-", "-", source})
+			continue
+		}
+		for _, route := range m.Routes {
+			path := route.Path
+			if len(path) < 1 {
+				path = "(ApiProxy not found)"
+			}
+			table.Append([]string{m.Name, m.Namespace, fmt.Sprintf("%s/%s", route.Namespace, route.Binding), route.Proxy, path, source})
+		}
+	}
+
+	table.Render()
+}
+
+// routes returns the routes of every ApiKeyBinding that references the
+// named API key. An ApiKeyBinding refers to an ApiProxy in its own namespace.
+func (r Resources) routes(name string) []Route {
+	routes := []Route{}
+	for _, binding := range r.Bindings {
+		if !references(binding, name) {
+			continue
+		}
+		route := Route{
+			Binding:   binding.ObjectMeta.Name,
+			Namespace: binding.ObjectMeta.Namespace,
+			Proxy:     binding.Spec.APIProxyName,
+		}
+		for _, proxy := range r.Proxies {
+			if proxy.ObjectMeta.Name == route.Proxy && proxy.ObjectMeta.Namespace == route.Namespace {
+				route.Path = proxy.Spec.Path
+				break
+			}
+		}
+		routes = append(routes, route)
+	}
+	return routes
+}
+
+func references(binding spec.APIKeyBinding, name string) bool {
+	for _, key := range binding.Spec.Keys {
+		if key.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package identify
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/northwesternmutual/kanali/spec"
+	"github.com/northwesternmutual/kanalictl/pkg/decrypt"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/kubernetes/pkg/api"
+)
+
+func TestIdentify(t *testing.T) {
+	results := []decrypt.Result{
+		{Name: "foo", Namespace: "a", Source: "keys.yaml", Document: 0, Status: decrypt.StatusDecrypted, Key: "secret"},
+		{Name: "bar", Namespace: "a", Source: "keys.yaml", Document: 1, Status: decrypt.StatusDecrypted, Key: "secret2"},
+		{Name: "baz", Namespace: "b", Source: "keys.yaml", Document: 2, Status: decrypt.StatusFailed, Error: "decryption error"},
+	}
+	resources := Resources{
+		Bindings: []spec.APIKeyBinding{
+			{
+				ObjectMeta: api.ObjectMeta{Name: "one", Namespace: "a"},
+				Spec:       spec.APIKeyBindingSpec{APIProxyName: "proxy", Keys: []spec.Key{{Name: "bar"}, {Name: "foo"}}},
+			},
+			{
+				ObjectMeta: api.ObjectMeta{Name: "two", Namespace: "b"},
+				Spec:       spec.APIKeyBindingSpec{APIProxyName: "proxy", Keys: []spec.Key{{Name: "foo"}}},
+			},
+			{
+				ObjectMeta: api.ObjectMeta{Name: "three", Namespace: "a"},
+				Spec:       spec.APIKeyBindingSpec{APIProxyName: "other", Keys: []spec.Key{{Name: "bar"}}},
+			},
+		},
+		Proxies: []spec.APIProxy{
+			{ObjectMeta: api.ObjectMeta{Name: "proxy", Namespace: "a"}, Spec: spec.APIProxySpec{Path: "/a"}},
+		},
+	}
+
+	matches := Identify(results, []byte("secret"), resources)
+	assert.Equal(t, matches, []Match{{
+		Name:      "foo",
+		Namespace: "a",
+		Source:    "keys.yaml",
+		Document:  0,
+		Routes: []Route{
+			{Binding: "one", Namespace: "a", Proxy: "proxy", Path: "/a"},
+			{Binding: "two", Namespace: "b", Proxy: "proxy"},
+		},
+	}})
+
+	assert.Empty(t, Identify(results, []byte("secre"), resources))
+	assert.Empty(t, Identify(results, []byte(""), resources))
+
+	var buf bytes.Buffer
+	Render(&buf, matches)
+	assert.True(t, strings.Contains(buf.String(), "a/one"))
+	assert.True(t, strings.Contains(buf.String(), "(ApiProxy not found)"))
+	assert.False(t, strings.Contains(buf.String(), "secret"))
+
+	buf.Reset()
+	Render(&buf, nil)
+	assert.Equal(t, buf.String(), "no API key matches the presented API key\n")
+}
+
+func TestFromFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "identify")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	data := `apiVersion: kanali.io/v1
+kind: ApiProxy
+metadata:
+  name: proxy
+  namespace: a
+spec:
+  path: /a
+---
+apiVersion: kanali.io/v1
+kind: ApiKeyBinding
+metadata:
+  name: one
+  namespace: a
+spec:
+  proxy: proxy
+  keys:
+  - name: foo
+---
+apiVersion: kanali.io/v1
+kind: ApiKey
+metadata:
+  name: foo
+spec:
+  data: abc
+`
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "resources.yaml"), []byte(data), 0644))
+
+	resources, err := FromFiles(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, len(resources.Bindings), 1)
+	assert.Equal(t, len(resources.Proxies), 1)
+	assert.Equal(t, resources.routes("foo"), []Route{{Binding: "one", Namespace: "a", Proxy: "proxy", Path: "/a"}})
+}
@@ -0,0 +1,149 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package keygen
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+
+	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/api/unversioned"
+)
+
+// MinBits is the smallest RSA key size that will be generated.
+const MinBits = 2048
+
+// KeyPair is a generated RSA key pair along with its PEM encodings.
+type KeyPair struct {
+	PrivateKey *rsa.PrivateKey
+	PrivatePEM []byte
+	PublicPEM  []byte
+}
+
+// Generate creates an RSA key pair. The private key is PKCS#1 and the
+// public key PKIX encoded, which is what the Kanali gateway and kanalictl
+// expect.
+func Generate(bits int) (*KeyPair, error) {
+	if bits < MinBits {
+		return nil, fmt.Errorf("key size must be at least %d bits", MinBits)
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeyPair{
+		PrivateKey: key,
+		PrivatePEM: pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+		PublicPEM:  pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}),
+	}, nil
+}
+
+// Fingerprint returns the SHA-256 digest of the DER encoded public key, as
+// printed by openssl dgst -sha256 for the key in DER form.
+func Fingerprint(pub *rsa.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("SHA256:%x", sha256.Sum256(der)), nil
+}
+
+// CheckFiles ensures none of the given files exist so that an existing key
+// is never overwritten.
+func CheckFiles(files ...string) error {
+	for _, file := range files {
+		if len(file) < 1 {
+			continue
+		}
+		if _, err := os.Stat(file); err == nil {
+			return fmt.Errorf("%s already exists - refusing to overwrite it", file)
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteFiles writes the private key so that it is only readable by its
+// owner and the public key so that it is readable by everyone. Neither
+// will overwrite an existing file.
+func WriteFiles(kp *KeyPair, privateKeyFile, publicKeyFile string) error {
+	if err := CheckFiles(privateKeyFile, publicKeyFile); err != nil {
+		return err
+	}
+	if err := writeExclusive(privateKeyFile, kp.PrivatePEM, 0600); err != nil {
+		return err
+	}
+	return writeExclusive(publicKeyFile, kp.PublicPEM, 0644)
+}
+
+// Secret returns the Kubernetes Secret the Kanali gateway mounts to decrypt
+// API keys.
+func Secret(kp *KeyPair, name, namespace, dataKey string) api.Secret {
+	return api.Secret{
+		TypeMeta: unversioned.TypeMeta{
+			Kind:       "Secret",
+			APIVersion: "v1",
+		},
+		ObjectMeta: api.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Data: map[string][]byte{
+			dataKey: kp.PrivatePEM,
+		},
+		Type: api.SecretTypeOpaque,
+	}
+}
+
+// WriteSecret writes a Kubernetes Secret so that it is only readable by its
+// owner. It will not overwrite an existing file.
+func WriteSecret(path string, data []byte) error {
+	return writeExclusive(path, data, 0600)
+}
+
+func writeExclusive(path string, data []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	// the umask may have removed bits from perm when the file was created
+	return os.Chmod(path, perm)
+}
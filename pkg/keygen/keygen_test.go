@@ -0,0 +1,109 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package keygen
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/northwesternmutual/kanalictl/pkg/rsakey"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerate(t *testing.T) {
+	_, err := Generate(1024)
+	assert.Equal(t, err.Error(), "key size must be at least 2048 bits")
+
+	kp, err := Generate(MinBits)
+	assert.Nil(t, err)
+	assert.Equal(t, kp.PrivateKey.N.BitLen(), MinBits)
+
+	priv, err := rsakey.ParsePrivateKey(kp.PrivatePEM, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, priv.D, kp.PrivateKey.D)
+
+	pub, err := rsakey.ParsePublicKey(kp.PublicPEM)
+	assert.Nil(t, err)
+	assert.Equal(t, pub, &kp.PrivateKey.PublicKey)
+
+	block, _ := pem.Decode(kp.PublicPEM)
+	fingerprint, err := Fingerprint(pub)
+	assert.Nil(t, err)
+	assert.Equal(t, fingerprint, fmt.Sprintf("SHA256:%x", sha256.Sum256(block.Bytes)))
+
+	_, err = x509.ParsePKIXPublicKey(block.Bytes)
+	assert.Nil(t, err)
+}
+
+func TestWriteFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keygen")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	kp, err := Generate(MinBits)
+	assert.Nil(t, err)
+
+	privateKeyFile := filepath.Join(dir, "private.pem")
+	publicKeyFile := filepath.Join(dir, "public.pem")
+	assert.Nil(t, WriteFiles(kp, privateKeyFile, publicKeyFile))
+
+	info, err := os.Stat(privateKeyFile)
+	assert.Nil(t, err)
+	assert.Equal(t, info.Mode().Perm(), os.FileMode(0600))
+	info, err = os.Stat(publicKeyFile)
+	assert.Nil(t, err)
+	assert.Equal(t, info.Mode().Perm(), os.FileMode(0644))
+
+	err = WriteFiles(kp, filepath.Join(dir, "other.pem"), publicKeyFile)
+	assert.Equal(t, err.Error(), publicKeyFile+" already exists - refusing to overwrite it")
+	_, err = os.Stat(filepath.Join(dir, "other.pem"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestSecret(t *testing.T) {
+	kp, err := Generate(MinBits)
+	assert.Nil(t, err)
+
+	data, err := yaml.Marshal(Secret(kp, "kanali-decryption-key", "kanali", "key.pem"))
+	assert.Nil(t, err)
+
+	var secret struct {
+		APIVersion string            `json:"apiVersion"`
+		Kind       string            `json:"kind"`
+		Metadata   map[string]string `json:"metadata"`
+		Data       map[string][]byte `json:"data"`
+		Type       string            `json:"type"`
+	}
+	assert.Nil(t, yaml.Unmarshal(data, &secret))
+	assert.Equal(t, secret.APIVersion, "v1")
+	assert.Equal(t, secret.Kind, "Secret")
+	assert.Equal(t, secret.Metadata["name"], "kanali-decryption-key")
+	assert.Equal(t, secret.Metadata["namespace"], "kanali")
+	assert.Equal(t, secret.Data["key.pem"], kp.PrivatePEM)
+	assert.Equal(t, secret.Type, "Opaque")
+}
@@ -0,0 +1,159 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Stdin is the input that reads from standard input.
+const Stdin = "-"
+
+var (
+	// stdin is where Stdin is read from.
+	stdin io.Reader = os.Stdin
+
+	errStdinWrite = errors.New("resources read from stdin cannot be written back")
+)
+
+// Expand returns the files named by inputs in the order they were given,
+// each at most once. An input may be a file, a directory, a glob pattern or
+// Stdin. Only YAML and JSON files are taken from directories and, unless
+// recursive is set, only from the top level of a directory.
+func Expand(inputs []string, recursive bool) ([]string, error) {
+	files := []string{}
+	seen := map[string]bool{}
+	add := func(file string) {
+		if !seen[file] {
+			seen[file] = true
+			files = append(files, file)
+		}
+	}
+
+	for _, input := range inputs {
+		if input == Stdin {
+			add(Stdin)
+			continue
+		}
+
+		paths := []string{input}
+		if strings.ContainsAny(input, "*?[") {
+			matches, err := filepath.Glob(input)
+			if err != nil {
+				return nil, err
+			}
+			if len(matches) < 1 {
+				return nil, fmt.Errorf("no files match %s", input)
+			}
+			paths = matches
+		}
+
+		for _, path := range paths {
+			info, err := os.Stat(path)
+			if err != nil {
+				return nil, err
+			}
+			if !info.IsDir() {
+				add(path)
+				continue
+			}
+
+			found, err := walk(path, recursive)
+			if err != nil {
+				return nil, err
+			}
+			for _, file := range found {
+				add(file)
+			}
+		}
+	}
+
+	return files, nil
+}
+
+// Load reads every file named by inputs as Expand finds them.
+func Load(inputs []string, recursive bool) ([]*File, error) {
+	fileList, err := Expand(inputs, recursive)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]*File, 0, len(fileList))
+	for _, file := range fileList {
+		f, err := Read(file)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+
+	return files, nil
+}
+
+// walk returns the YAML and JSON files in a directory.
+func walk(dir string, recursive bool) ([]string, error) {
+	fileList := []string{}
+
+	err := filepath.Walk(dir, func(path string, f os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if f.IsDir() {
+			if path != dir && !recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isManifest(path) {
+			fileList = append(fileList, path)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return fileList, nil
+}
+
+func isManifest(path string) bool {
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+// readStdin reads standard input as a file that cannot be written back.
+func readStdin() (*File, error) {
+	data, err := ioutil.ReadAll(stdin)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(Stdin, data), nil
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package manifest
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "manifest")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, file := range []string{"a.yaml", "b.json", "notes.txt", "team/c.yml", "team/nested/d.yaml"} {
+		path := filepath.Join(dir, file)
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.Nil(t, ioutil.WriteFile(path, []byte("kind: ApiKey\n"), 0644))
+	}
+	join := func(files ...string) []string {
+		paths := []string{}
+		for _, file := range files {
+			paths = append(paths, filepath.Join(dir, file))
+		}
+		return paths
+	}
+
+	files, err := Expand([]string{dir}, false)
+	assert.Nil(t, err)
+	assert.Equal(t, files, join("a.yaml", "b.json"))
+
+	files, err = Expand([]string{dir}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, files, join("a.yaml", "b.json", "team/c.yml", "team/nested/d.yaml"))
+
+	files, err = Expand([]string{filepath.Join(dir, "team"), filepath.Join(dir, "*.yaml"), filepath.Join(dir, "notes.txt"), Stdin, filepath.Join(dir, "a.yaml")}, false)
+	assert.Nil(t, err)
+	assert.Equal(t, files, append(join("team/c.yml", "a.yaml", "notes.txt"), Stdin))
+
+	_, err = Expand([]string{filepath.Join(dir, "*.xml")}, false)
+	assert.Equal(t, err.Error(), "no files match "+filepath.Join(dir, "*.xml"))
+
+	_, err = Expand([]string{filepath.Join(dir, "missing.yaml")}, false)
+	assert.NotNil(t, err)
+}
+
+func TestLoadStdin(t *testing.T) {
+	defer func() { stdin = os.Stdin }()
+	stdin = bytes.NewBufferString("kind: ApiKey\n---\nkind: ApiProxy\n")
+
+	files, err := Load([]string{Stdin}, false)
+	assert.Nil(t, err)
+	assert.Equal(t, len(files), 1)
+	assert.Equal(t, files[0].Path, Stdin)
+	assert.Equal(t, files[0].Documents[1].Kind(), "ApiProxy")
+	assert.Equal(t, files[0].Write(), errStdinWrite)
+}
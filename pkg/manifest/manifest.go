@@ -0,0 +1,204 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package manifest
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"k8s.io/kubernetes/pkg/api/unversioned"
+)
+
+var separator = []byte("---")
+
+// Document is a single YAML document within a file.
+type Document struct {
+	// Index is the position of the document within its file.
+	Index int
+	Data  []byte
+
+	// separator is the line, if any, that preceded the document.
+	separator []byte
+}
+
+// File is a YAML or JSON file containing one or more documents.
+type File struct {
+	Path      string
+	Documents []*Document
+	mode      os.FileMode
+}
+
+// Discover returns every YAML or JSON file found recursively
+// under the specified file or directory.
+func Discover(path string) ([]string, error) {
+	return Expand([]string{path}, true)
+}
+
+// Read reads and splits a file into its documents. If path is Stdin,
+// standard input is read instead.
+func Read(path string) (*File, error) {
+	if path == Stdin {
+		return readStdin()
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f := Parse(path, data)
+	f.mode = info.Mode().Perm()
+	return f, nil
+}
+
+// Parse splits data into its documents. Documents are separated by lines
+// beginning with "---". Everything else, including comments, is kept so
+// that the file can be written back without disturbing its layout. As a
+// result, a file may contain documents that are empty.
+func Parse(path string, data []byte) *File {
+	f := &File{
+		Path: path,
+		mode: 0644,
+	}
+
+	doc := &Document{}
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		if len(line) < 1 {
+			continue
+		}
+		if isSeparator(line) {
+			f.Documents = append(f.Documents, doc)
+			doc = &Document{
+				separator: line,
+			}
+			continue
+		}
+		doc.Data = append(doc.Data, line...)
+	}
+	f.Documents = append(f.Documents, doc)
+
+	f.reindex()
+	return f
+}
+
+// Bytes reassembles the documents of the file.
+func (f *File) Bytes() []byte {
+	buf := &bytes.Buffer{}
+	for i, doc := range f.Documents {
+		sep := doc.separator
+		if i > 0 && len(sep) < 1 {
+			sep = append(separator, '\n')
+		}
+		if len(sep) > 0 && buf.Len() > 0 && !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+			buf.WriteByte('\n')
+		}
+		buf.Write(sep)
+		buf.Write(doc.Data)
+	}
+	return buf.Bytes()
+}
+
+// Write writes the file back to disk with its original permissions.
+func (f *File) Write() error {
+	if f.Path == Stdin {
+		return errStdinWrite
+	}
+	return ioutil.WriteFile(f.Path, f.Bytes(), f.mode)
+}
+
+// JSON reports whether the file is a JSON file, whose single document must
+// be written as JSON.
+func (f *File) JSON() bool {
+	return strings.EqualFold(filepath.Ext(f.Path), ".json")
+}
+
+// Insert adds a document after the document at the given index.
+func (f *File) Insert(after int, data []byte) {
+	doc := &Document{
+		Data: data,
+	}
+	f.Documents = append(f.Documents, nil)
+	copy(f.Documents[after+2:], f.Documents[after+1:])
+	f.Documents[after+1] = doc
+	f.reindex()
+}
+
+// Remove removes the document at the given index.
+func (f *File) Remove(index int) {
+	f.Documents = append(f.Documents[:index], f.Documents[index+1:]...)
+	if index == 0 && len(f.Documents) > 0 {
+		f.Documents[0].separator = nil
+	}
+	f.reindex()
+}
+
+// Empty reports whether the file contains no resources.
+func (f *File) Empty() bool {
+	for _, doc := range f.Documents {
+		if !doc.Empty() {
+			return false
+		}
+	}
+	return true
+}
+
+// Kind returns the Kubernetes kind of the document, if any.
+func (d *Document) Kind() string {
+	var meta unversioned.TypeMeta
+	if err := yaml.Unmarshal(d.Data, &meta); err != nil {
+		return ""
+	}
+	return meta.Kind
+}
+
+// Empty reports whether the document contains only whitespace and comments.
+func (d *Document) Empty() bool {
+	for _, line := range bytes.Split(d.Data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 && line[0] != '#' {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *File) reindex() {
+	for i, doc := range f.Documents {
+		doc.Index = i
+	}
+}
+
+func isSeparator(line []byte) bool {
+	if !bytes.HasPrefix(line, separator) {
+		return false
+	}
+	rest := bytes.TrimSpace(line[len(separator):])
+	return len(rest) < 1 || rest[0] == '#'
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package manifest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	data := "# keys\n---\napiVersion: kanali.io/v1\nkind: ApiKey\n--- # second\n# comment\nkind: ApiProxy\n---\n"
+	f := Parse("foo.yaml", []byte(data))
+
+	assert.Equal(t, len(f.Documents), 4)
+	assert.True(t, f.Documents[0].Empty())
+	assert.Equal(t, f.Documents[1].Kind(), "ApiKey")
+	assert.Equal(t, f.Documents[2].Kind(), "ApiProxy")
+	assert.Equal(t, f.Documents[2].Index, 2)
+	assert.True(t, f.Documents[3].Empty())
+	assert.False(t, f.Empty())
+	assert.Equal(t, string(f.Bytes()), data)
+
+	f.Insert(1, []byte("kind: ApiKeyBinding\n"))
+	assert.Equal(t, f.Documents[2].Kind(), "ApiKeyBinding")
+	assert.Equal(t, f.Documents[3].Index, 3)
+	assert.Equal(t, string(f.Bytes()), "# keys\n---\napiVersion: kanali.io/v1\nkind: ApiKey\n---\nkind: ApiKeyBinding\n--- # second\n# comment\nkind: ApiProxy\n---\n")
+
+	f.Remove(1)
+	f.Remove(1)
+	f.Remove(1)
+	assert.True(t, f.Empty())
+}
+
+func TestParseSingleDocument(t *testing.T) {
+	data := "kind: ApiKey\nspec:\n  data: abc"
+	f := Parse("foo.yaml", []byte(data))
+	assert.Equal(t, len(f.Documents), 1)
+	assert.Equal(t, f.Documents[0].Kind(), "ApiKey")
+	assert.Equal(t, string(f.Bytes()), data)
+
+	f.Insert(0, []byte("kind: ApiKey\n"))
+	assert.Equal(t, string(f.Bytes()), data+"\n---\nkind: ApiKey\n")
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package rewrap
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ghodss/yaml"
+	"github.com/northwesternmutual/kanali/spec"
+	"github.com/northwesternmutual/kanalictl/pkg/decrypt"
+	"github.com/northwesternmutual/kanalictl/pkg/generate"
+	"github.com/northwesternmutual/kanalictl/pkg/manifest"
+)
+
+// Result describes the API keys within a single file
+// that were, or would be, re-encrypted.
+type Result struct {
+	File   string
+	Keys   []string
+	Errors []error
+}
+
+// Do re-encrypts every API key resource recursively found under the
+// specified file or directory. Each API key is decrypted using the old
+// private key and encrypted using the new public key. Only the data of each
+// API key is changed, every other document and line is left untouched. If
+// check is true, no files are written.
+func Do(path string, oldKey *rsa.PrivateKey, newKey *rsa.PublicKey, check bool) ([]Result, error) {
+	fileList, err := manifest.Discover(path)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []Result{}
+
+	for _, file := range fileList {
+		f, err := manifest.Read(file)
+		if err != nil {
+			return nil, err
+		}
+
+		result := rewrapFile(f, oldKey, newKey)
+		if len(result.Keys) < 1 && len(result.Errors) < 1 {
+			continue
+		}
+
+		if !check && len(result.Keys) > 0 {
+			if err := f.Write(); err != nil {
+				return nil, err
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func rewrapFile(f *manifest.File, oldKey *rsa.PrivateKey, newKey *rsa.PublicKey) Result {
+	result := Result{
+		File: f.Path,
+	}
+
+	for _, doc := range f.Documents {
+		if doc.Kind() != "ApiKey" {
+			continue
+		}
+
+		var apikey spec.APIKey
+		if err := yaml.Unmarshal(doc.Data, &apikey); err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("document %d: %s", doc.Index, err.Error()))
+			continue
+		}
+
+		data, err := rewrapDocument(doc.Data, apikey, f.JSON(), oldKey, newKey)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("ApiKey %s: %s", apikey.ObjectMeta.Name, err.Error()))
+			continue
+		}
+
+		doc.Data = data
+		result.Keys = append(result.Keys, apikey.ObjectMeta.Name)
+	}
+
+	return result
+}
+
+// rewrapDocument returns the document with the data of the API key re-encrypted.
+// The encrypted data is replaced in place so that comments and formatting are
+// kept. Should the encrypted data not appear verbatim in the document, the
+// document is re-serialized instead, as JSON if isJSON is true and as YAML
+// otherwise.
+func rewrapDocument(data []byte, apikey spec.APIKey, isJSON bool, oldKey *rsa.PrivateKey, newKey *rsa.PublicKey) ([]byte, error) {
+	unencryptedKeyData, err := decrypt.KeyData(apikey.Spec.APIKeyData, oldKey)
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt using the old private key: %s", err.Error())
+	}
+
+	encryptedKeyData, err := generate.EncryptKeyData(unencryptedKeyData, newKey)
+	if err != nil {
+		return nil, err
+	}
+	newData := fmt.Sprintf("%x", encryptedKeyData)
+
+	if bytes.Count(data, []byte(apikey.Spec.APIKeyData)) == 1 {
+		return bytes.Replace(data, []byte(apikey.Spec.APIKeyData), []byte(newData), 1), nil
+	}
+
+	var obj map[string]interface{}
+	if err := yaml.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+	if spec, ok := obj["spec"].(map[string]interface{}); ok {
+		spec["data"] = newData
+	}
+
+	if !isJSON {
+		return yaml.Marshal(obj)
+	}
+	data, err = json.MarshalIndent(obj, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package rewrap
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/northwesternmutual/kanali/spec"
+	"github.com/northwesternmutual/kanalictl/pkg/decrypt"
+	"github.com/northwesternmutual/kanalictl/pkg/generate"
+	"github.com/northwesternmutual/kanalictl/pkg/manifest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDo(t *testing.T) {
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	encryptedKeyData, err := generate.EncryptKeyData([]byte("abc123"), &oldKey.PublicKey)
+	assert.Nil(t, err)
+
+	dir, err := ioutil.TempDir("", "kanalictl")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	proxy := "apiVersion: kanali.io/v1\nkind: ApiProxy\nmetadata:\n  name: example\nspec:\n  path: /example\n"
+	original := fmt.Sprintf("# owned by team a\napiVersion: kanali.io/v1\nkind: ApiKey\nmetadata:\n  name: frank\nspec:\n  data: %x # encrypted\n---\n%s", encryptedKeyData, proxy)
+	file := filepath.Join(dir, "keys.yaml")
+	assert.Nil(t, ioutil.WriteFile(file, []byte(original), 0600))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "proxy.yaml"), []byte(proxy), 0644))
+
+	results, err := Do(dir, oldKey, &newKey.PublicKey, true)
+	assert.Nil(t, err)
+	assert.Equal(t, results, []Result{{File: file, Keys: []string{"frank"}}})
+	data, err := ioutil.ReadFile(file)
+	assert.Nil(t, err)
+	assert.Equal(t, string(data), original)
+
+	results, err = Do(dir, oldKey, &newKey.PublicKey, false)
+	assert.Nil(t, err)
+	assert.Equal(t, len(results), 1)
+
+	f, err := manifest.Read(file)
+	assert.Nil(t, err)
+	assert.Equal(t, len(f.Documents), 2)
+	assert.True(t, strings.HasPrefix(string(f.Documents[0].Data), "# owned by team a\n"))
+	assert.Contains(t, string(f.Documents[0].Data), " # encrypted\n")
+	assert.Equal(t, string(f.Documents[1].Data), proxy)
+
+	info, err := os.Stat(file)
+	assert.Nil(t, err)
+	assert.Equal(t, info.Mode().Perm(), os.FileMode(0600))
+
+	var apikey spec.APIKey
+	assert.Nil(t, yaml.Unmarshal(f.Documents[0].Data, &apikey))
+	unencryptedKeyData, err := decrypt.KeyData(apikey.Spec.APIKeyData, newKey)
+	assert.Nil(t, err)
+	assert.Equal(t, string(unencryptedKeyData), "abc123")
+
+	results, err = Do(dir, oldKey, &newKey.PublicKey, false)
+	assert.Nil(t, err)
+	assert.Equal(t, len(results), 1)
+	assert.Equal(t, len(results[0].Keys), 0)
+	assert.Equal(t, len(results[0].Errors), 1)
+}
+
+func TestDoJSON(t *testing.T) {
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	encryptedKeyData, err := generate.EncryptKeyData([]byte("abc123"), &oldKey.PublicKey)
+	assert.Nil(t, err)
+
+	dir, err := ioutil.TempDir("", "kanalictl")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	// the encrypted data appears twice, so the document is re-serialized
+	original := fmt.Sprintf(`{"apiVersion": "kanali.io/v1", "kind": "ApiKey", "metadata": {"name": "frank", "annotations": {"previous": "%x"}}, "spec": {"data": "%x"}}`, encryptedKeyData, encryptedKeyData)
+	file := filepath.Join(dir, "key.json")
+	assert.Nil(t, ioutil.WriteFile(file, []byte(original), 0600))
+
+	_, err = Do(dir, oldKey, &newKey.PublicKey, false)
+	assert.Nil(t, err)
+
+	data, err := ioutil.ReadFile(file)
+	assert.Nil(t, err)
+	var apikey spec.APIKey
+	assert.Nil(t, json.Unmarshal(data, &apikey))
+	unencryptedKeyData, err := decrypt.KeyData(apikey.Spec.APIKeyData, newKey)
+	assert.Nil(t, err)
+	assert.Equal(t, string(unencryptedKeyData), "abc123")
+}
@@ -0,0 +1,489 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package rotate
+
+import (
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/northwesternmutual/kanali/spec"
+	"github.com/northwesternmutual/kanalictl/pkg/cluster"
+	"github.com/northwesternmutual/kanalictl/pkg/expiry"
+	"github.com/northwesternmutual/kanalictl/pkg/generate"
+	"github.com/northwesternmutual/kanalictl/pkg/manifest"
+)
+
+var successorRegex = regexp.MustCompile("^(.*)-r([0-9]+)$")
+
+// Key identifies an API key by its namespace and name. An empty namespace
+// is the default namespace. ApiKeyBindings reference API keys by name only,
+// so an ApiKeyBinding in any namespace can grant an API key access.
+type Key struct {
+	Namespace string
+	Name      string
+}
+
+// Change describes a modification made to a file while rotating an API key.
+type Change struct {
+	File        string
+	Description string
+}
+
+// Result is the outcome of starting the rotation of an API key.
+type Result struct {
+	Successor          spec.APIKey
+	UnencryptedKeyData []byte
+	Entropy            float64
+	Changes            []Change
+}
+
+// SuccessorName returns the name of the API key that replaces the named
+// API key. A key named foo is replaced by foo-r2, foo-r2 by foo-r3 and so on.
+func SuccessorName(name string) string {
+	if match := successorRegex.FindStringSubmatch(name); match != nil {
+		if n, err := strconv.Atoi(match[2]); err == nil {
+			return fmt.Sprintf("%s-r%d", match[1], n+1)
+		}
+	}
+	return name + "-r2"
+}
+
+// String returns the namespace and name of an API key.
+func (k Key) String() string {
+	return namespaceOf(k.Namespace) + "/" + k.Name
+}
+
+// successor returns the API key that replaces an API key.
+func (k Key) successor() Key {
+	return Key{Namespace: k.Namespace, Name: SuccessorName(k.Name)}
+}
+
+// Start begins the rotation of an API key found under path. A successor
+// API key is generated and written next to the existing API key. Every
+// ApiKeyBinding that grants the API key access grants the successor the same
+// access so that both keys are valid during the grace period.
+func Start(path string, key Key, length int, policy generate.Policy, encryptKey *rsa.PublicKey) (*Result, error) {
+	files, err := readFiles(path)
+	if err != nil {
+		return nil, err
+	}
+
+	next := key.successor()
+	successorName := next.Name
+
+	if existing := findKeys(files, next); len(existing) > 0 {
+		return nil, fmt.Errorf("ApiKey %s already exists in %s - rotation of %s has already been started", next, existing[0].file.Path, key)
+	}
+
+	keys := findKeys(files, key)
+	if len(keys) < 1 {
+		return nil, fmt.Errorf("ApiKey %s not found under %s", key, path)
+	} else if len(keys) > 1 {
+		return nil, fmt.Errorf("ApiKey %s is defined more than once under %s", key, path)
+	}
+	old := keys[0]
+
+	// a JSON file holds a single resource, so the successor cannot be
+	// written next to the existing API key
+	if old.file.JSON() {
+		return nil, fmt.Errorf("ApiKey %s is defined in the JSON file %s, which cannot hold its successor - convert it to YAML to rotate it", key, old.file.Path)
+	}
+
+	unencryptedKeyData, encryptedKeyData, entropy, err := generate.Key(successorName, "", length, policy, encryptKey)
+	if err != nil {
+		return nil, err
+	}
+
+	successor := generate.CRD(successorName, generate.Metadata{
+		Namespace:   old.key.ObjectMeta.Namespace,
+		Labels:      old.key.ObjectMeta.Labels,
+		Annotations: expiry.Renew(old.key.ObjectMeta.Annotations, time.Now()),
+	}, encryptedKeyData)
+
+	successorData, err := yaml.Marshal(successor)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		Successor:          successor,
+		UnencryptedKeyData: unencryptedKeyData,
+		Entropy:            entropy,
+	}
+
+	bindings, err := findBindings(files, key.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := map[*manifest.File]bool{}
+
+	for _, binding := range bindings {
+		keys := binding.keys()
+		updated := make([]interface{}, 0, len(keys)+1)
+		for i, granted := range binding.binding.Spec.Keys {
+			updated = append(updated, keys[i])
+			if granted.Name != key.Name {
+				continue
+			}
+			successorKey, err := copyKey(keys[i])
+			if err != nil {
+				return nil, err
+			}
+			successorKey["name"] = successorName
+			updated = append(updated, successorKey)
+		}
+
+		if err := binding.setKeys(updated); err != nil {
+			return nil, err
+		}
+
+		changed[binding.file] = true
+		result.Changes = append(result.Changes, Change{
+			File:        binding.file.Path,
+			Description: fmt.Sprintf("added ApiKey %s to ApiKeyBinding %s", successorName, binding.name()),
+		})
+	}
+
+	old.file.Insert(old.doc.Index, successorData)
+	changed[old.file] = true
+	result.Changes = append(result.Changes, Change{
+		File:        old.file.Path,
+		Description: fmt.Sprintf("added ApiKey %s", successorName),
+	})
+
+	if err := writeFiles(files, changed); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Finalize completes the rotation of an API key found under path. The API
+// key is removed from every ApiKeyBinding and its config is deleted. Every
+// ApiKeyBinding that grants the API key access must already grant access to
+// the successor API key.
+func Finalize(path string, key Key) ([]Change, error) {
+	files, err := readFiles(path)
+	if err != nil {
+		return nil, err
+	}
+
+	next := key.successor()
+
+	if len(findKeys(files, next)) < 1 {
+		return nil, fmt.Errorf("ApiKey %s not found under %s - rotation of %s has not been started", next, path, key)
+	}
+
+	bindings, err := findBindings(files, key.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	problems := []string{}
+	for _, binding := range bindings {
+		if !binding.references(next.Name) {
+			problems = append(problems, fmt.Sprintf("ApiKeyBinding %s in %s does not grant access to ApiKey %s", binding.name(), binding.file.Path, next))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, errors.New(strings.Join(problems, "\n"))
+	}
+
+	return remove(files, key)
+}
+
+// Remove removes API keys found under path from every ApiKeyBinding and
+// deletes their configs.
+func Remove(path string, keys ...Key) ([]Change, error) {
+	files, err := readFiles(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range keys {
+		if len(findKeys(files, key)) < 1 {
+			return nil, fmt.Errorf("ApiKey %s not found under %s", key, path)
+		}
+	}
+
+	return remove(files, keys...)
+}
+
+func remove(files []*manifest.File, targets ...Key) ([]Change, error) {
+	changes := []Change{}
+	changed := map[*manifest.File]bool{}
+	removed := map[*manifest.Document]bool{}
+
+	for _, target := range targets {
+		bindings, err := findBindings(files, target.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, binding := range bindings {
+			if removed[binding.doc] {
+				continue
+			}
+
+			keys := binding.keys()
+			updated := make([]interface{}, 0, len(keys))
+			for i, key := range binding.binding.Spec.Keys {
+				if key.Name != target.Name {
+					updated = append(updated, keys[i])
+				}
+			}
+
+			changed[binding.file] = true
+
+			// an ApiKeyBinding must grant at least one API key access
+			if len(updated) < 1 {
+				removed[binding.doc] = true
+				changes = append(changes, Change{
+					File:        binding.file.Path,
+					Description: fmt.Sprintf("removed ApiKeyBinding %s, which has no API keys left", binding.name()),
+				})
+				continue
+			}
+
+			if err := binding.setKeys(updated); err != nil {
+				return nil, err
+			}
+
+			changes = append(changes, Change{
+				File:        binding.file.Path,
+				Description: fmt.Sprintf("removed ApiKey %s from ApiKeyBinding %s", target.Name, binding.name()),
+			})
+		}
+
+		for _, key := range findKeys(files, target) {
+			removed[key.doc] = true
+			changed[key.file] = true
+			changes = append(changes, Change{
+				File:        key.file.Path,
+				Description: fmt.Sprintf("removed ApiKey %s", target.Name),
+			})
+		}
+	}
+
+	// remove documents from the end of each file first so that the indexes
+	// of the remaining documents remain valid
+	for _, f := range files {
+		for i := len(f.Documents) - 1; i >= 0; i-- {
+			if removed[f.Documents[i]] {
+				f.Remove(i)
+			}
+		}
+	}
+
+	if err := writeFiles(files, changed); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+type keyDocument struct {
+	file *manifest.File
+	doc  *manifest.Document
+	key  spec.APIKey
+}
+
+// bindingDocument is an ApiKeyBinding found in a file. The binding is used to
+// find the API keys it grants access to, while changes are made to the
+// document itself so that fields kanalictl does not know about are kept.
+type bindingDocument struct {
+	file    *manifest.File
+	doc     *manifest.Document
+	binding spec.APIKeyBinding
+	obj     map[string]interface{}
+}
+
+func (b *bindingDocument) name() string {
+	return b.binding.ObjectMeta.Name
+}
+
+// keys returns the API keys of the document, in the same order as the API
+// keys of the binding.
+func (b *bindingDocument) keys() []interface{} {
+	if spec, ok := b.obj["spec"].(map[string]interface{}); ok {
+		if keys, ok := spec["keys"].([]interface{}); ok {
+			return keys
+		}
+	}
+	return nil
+}
+
+func (b *bindingDocument) setKeys(keys []interface{}) error {
+	spec, ok := b.obj["spec"].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("ApiKeyBinding %s in %s does not contain a spec", b.name(), b.file.Path)
+	}
+	spec["keys"] = keys
+
+	data, err := marshal(b.file, b.obj)
+	if err != nil {
+		return err
+	}
+	b.doc.Data = data
+
+	return nil
+}
+
+func (b *bindingDocument) references(name string) bool {
+	for _, key := range b.binding.Spec.Keys {
+		if key.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func readFiles(path string) ([]*manifest.File, error) {
+	fileList, err := manifest.Discover(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]*manifest.File, 0, len(fileList))
+	for _, file := range fileList {
+		f, err := manifest.Read(file)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+
+	return files, nil
+}
+
+func writeFiles(files []*manifest.File, changed map[*manifest.File]bool) error {
+	for _, f := range files {
+		if !changed[f] {
+			continue
+		}
+		if f.Empty() {
+			if err := os.Remove(f.Path); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := f.Write(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func findKeys(files []*manifest.File, target Key) []keyDocument {
+	keys := []keyDocument{}
+	for _, f := range files {
+		for _, doc := range f.Documents {
+			if doc.Kind() != "ApiKey" {
+				continue
+			}
+			var key spec.APIKey
+			if err := yaml.Unmarshal(doc.Data, &key); err != nil {
+				continue
+			}
+			if key.ObjectMeta.Name == target.Name && namespaceOf(key.ObjectMeta.Namespace) == namespaceOf(target.Namespace) {
+				keys = append(keys, keyDocument{
+					file: f,
+					doc:  doc,
+					key:  key,
+				})
+			}
+		}
+	}
+	return keys
+}
+
+// findBindings returns every ApiKeyBinding that grants the named API key
+// access, whatever its namespace, since API keys are referenced by name only.
+func findBindings(files []*manifest.File, name string) ([]*bindingDocument, error) {
+	bindings := []*bindingDocument{}
+	for _, f := range files {
+		for _, doc := range f.Documents {
+			if doc.Kind() != "ApiKeyBinding" {
+				continue
+			}
+			binding := &bindingDocument{
+				file: f,
+				doc:  doc,
+			}
+			if err := yaml.Unmarshal(doc.Data, &binding.binding); err != nil {
+				return nil, fmt.Errorf("ApiKeyBinding in %s could not be parsed: %s", f.Path, err.Error())
+			}
+			if err := yaml.Unmarshal(doc.Data, &binding.obj); err != nil {
+				return nil, fmt.Errorf("ApiKeyBinding %s in %s could not be parsed: %s", binding.name(), f.Path, err.Error())
+			}
+			if len(binding.keys()) != len(binding.binding.Spec.Keys) {
+				return nil, fmt.Errorf("ApiKeyBinding %s in %s has malformed keys", binding.name(), f.Path)
+			}
+			if binding.references(name) {
+				bindings = append(bindings, binding)
+			}
+		}
+	}
+	return bindings, nil
+}
+
+// marshal returns a resource as the document of a file, in JSON if it is a
+// JSON file and in YAML otherwise.
+func marshal(f *manifest.File, obj interface{}) ([]byte, error) {
+	if !f.JSON() {
+		return yaml.Marshal(obj)
+	}
+	data, err := json.MarshalIndent(obj, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// namespaceOf returns the namespace a resource is in, which is the default
+// namespace if its configuration does not name one.
+func namespaceOf(namespace string) string {
+	if len(namespace) < 1 {
+		return cluster.DefaultNamespace
+	}
+	return namespace
+}
+
+func copyKey(key interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(key)
+	if err != nil {
+		return nil, err
+	}
+	var c map[string]interface{}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
@@ -0,0 +1,322 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package rotate
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/northwesternmutual/kanali/spec"
+	"github.com/northwesternmutual/kanalictl/pkg/generate"
+	"github.com/northwesternmutual/kanalictl/pkg/manifest"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testKeys = `# keys owned by team a
+apiVersion: kanali.io/v1
+kind: ApiKey
+metadata:
+  name: frank
+  namespace: application
+  labels:
+    team: a
+spec:
+  data: abc
+`
+	testBindings = `apiVersion: kanali.io/v1
+kind: ApiKeyBinding
+metadata:
+  name: example
+  namespace: application
+spec:
+  proxy: example
+  keys:
+  - name: frank
+    quota: 100
+    rate:
+      amount: 10
+      unit: minute
+    subpaths:
+    - path: /foo
+      rule:
+        granular:
+          verbs:
+          - GET
+  - name: other
+    defaultRule:
+      global: true
+---
+apiVersion: kanali.io/v1
+kind: ApiProxy
+metadata:
+  name: example
+  namespace: application
+spec:
+  path: /example
+`
+)
+
+func TestSuccessorName(t *testing.T) {
+	assert.Equal(t, SuccessorName("frank"), "frank-r2")
+	assert.Equal(t, SuccessorName("frank-r2"), "frank-r3")
+	assert.Equal(t, SuccessorName("frank-r99"), "frank-r100")
+	assert.Equal(t, SuccessorName("frank-rx"), "frank-rx-r2")
+}
+
+func TestRotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kanalictl")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	keysFile := filepath.Join(dir, "keys.yaml")
+	bindingsFile := filepath.Join(dir, "bindings.yaml")
+	assert.Nil(t, ioutil.WriteFile(keysFile, []byte(testKeys), 0644))
+	assert.Nil(t, ioutil.WriteFile(bindingsFile, []byte(testBindings), 0644))
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	frank := Key{Namespace: "application", Name: "frank"}
+
+	_, err = Finalize(dir, frank)
+	assert.Equal(t, err.Error(), "ApiKey application/frank-r2 not found under "+dir+" - rotation of application/frank has not been started")
+
+	_, err = Start(dir, Key{Namespace: "application", Name: "missing"}, 32, generate.DefaultPolicy(), &privateKey.PublicKey)
+	assert.Equal(t, err.Error(), "ApiKey application/missing not found under "+dir)
+
+	_, err = Start(dir, Key{Name: "frank"}, 32, generate.DefaultPolicy(), &privateKey.PublicKey)
+	assert.Equal(t, err.Error(), "ApiKey default/frank not found under "+dir)
+
+	result, err := Start(dir, frank, 32, generate.DefaultPolicy(), &privateKey.PublicKey)
+	assert.Nil(t, err)
+	assert.Equal(t, len(result.UnencryptedKeyData), 32)
+	assert.Equal(t, result.Successor.ObjectMeta.Name, "frank-r2")
+	assert.Equal(t, result.Successor.ObjectMeta.Namespace, "application")
+	assert.Equal(t, result.Successor.ObjectMeta.Labels, map[string]string{"team": "a"})
+	assert.Equal(t, len(result.Changes), 2)
+
+	_, err = Start(dir, frank, 32, generate.DefaultPolicy(), &privateKey.PublicKey)
+	assert.Equal(t, err.Error(), "ApiKey application/frank-r2 already exists in "+keysFile+" - rotation of application/frank has already been started")
+
+	f, err := manifest.Read(keysFile)
+	assert.Nil(t, err)
+	assert.Equal(t, len(f.Documents), 2)
+	assert.Contains(t, string(f.Documents[0].Data), "# keys owned by team a")
+
+	binding := readBinding(t, bindingsFile)
+	assert.Equal(t, len(binding.Spec.Keys), 3)
+	assert.Equal(t, binding.Spec.Keys[1].Name, "frank-r2")
+	assert.Equal(t, binding.Spec.Keys[1].Quota, 100)
+	assert.Equal(t, *binding.Spec.Keys[1].Rate, spec.Rate{Amount: 10, Unit: "minute"})
+	assert.Equal(t, binding.Spec.Keys[1].Subpaths[0].Path, "/foo")
+	assert.Equal(t, binding.Spec.Keys[1].Subpaths[0].Rule.Granular.Verbs, []string{"GET"})
+
+	changes, err := Finalize(dir, frank)
+	assert.Nil(t, err)
+	assert.Equal(t, len(changes), 2)
+
+	binding = readBinding(t, bindingsFile)
+	assert.Equal(t, len(binding.Spec.Keys), 2)
+	assert.Equal(t, binding.Spec.Keys[0].Name, "frank-r2")
+	assert.Equal(t, binding.Spec.Keys[1].Name, "other")
+
+	f, err = manifest.Read(keysFile)
+	assert.Nil(t, err)
+	assert.Equal(t, len(f.Documents), 1)
+	assert.Contains(t, string(f.Documents[0].Data), "name: frank-r2")
+}
+
+func TestRemove(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kanalictl")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	keysFile := filepath.Join(dir, "keys.yaml")
+	bindingsFile := filepath.Join(dir, "bindings.yaml")
+	assert.Nil(t, ioutil.WriteFile(keysFile, []byte(testKeys), 0644))
+	assert.Nil(t, ioutil.WriteFile(bindingsFile, []byte(testBindings), 0644))
+
+	frank := Key{Namespace: "application", Name: "frank"}
+
+	_, err = Remove(dir, frank, Key{Namespace: "application", Name: "missing"})
+	assert.Equal(t, err.Error(), "ApiKey application/missing not found under "+dir)
+
+	changes, err := Remove(dir, frank)
+	assert.Nil(t, err)
+	assert.Equal(t, changes, []Change{
+		{File: bindingsFile, Description: "removed ApiKey frank from ApiKeyBinding example"},
+		{File: keysFile, Description: "removed ApiKey frank"},
+	})
+
+	binding := readBinding(t, bindingsFile)
+	assert.Equal(t, len(binding.Spec.Keys), 1)
+	assert.Equal(t, binding.Spec.Keys[0].Name, "other")
+
+	_, err = os.Stat(keysFile)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestRemoveLastKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kanalictl")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	keysFile := filepath.Join(dir, "keys.yaml")
+	bindingsFile := filepath.Join(dir, "bindings.yaml")
+	assert.Nil(t, ioutil.WriteFile(keysFile, []byte(testKeys+"---\n"+strings.Replace(testKeys, "name: frank", "name: other", 1)), 0644))
+	assert.Nil(t, ioutil.WriteFile(bindingsFile, []byte(testBindings), 0644))
+
+	changes, err := Remove(dir, Key{Namespace: "application", Name: "frank"}, Key{Namespace: "application", Name: "other"})
+	assert.Nil(t, err)
+	assert.Equal(t, changes, []Change{
+		{File: bindingsFile, Description: "removed ApiKey frank from ApiKeyBinding example"},
+		{File: keysFile, Description: "removed ApiKey frank"},
+		{File: bindingsFile, Description: "removed ApiKeyBinding example, which has no API keys left"},
+		{File: keysFile, Description: "removed ApiKey other"},
+	})
+
+	f, err := manifest.Read(bindingsFile)
+	assert.Nil(t, err)
+	assert.Equal(t, len(f.Documents), 1)
+	assert.Equal(t, f.Documents[0].Kind(), "ApiProxy")
+
+	_, err = os.Stat(keysFile)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestRotateNamespaces(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kanalictl")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	keysFile := filepath.Join(dir, "keys.yaml")
+	bindingsFile := filepath.Join(dir, "bindings.yaml")
+	otherFile := filepath.Join(dir, "other.yaml")
+	assert.Nil(t, ioutil.WriteFile(keysFile, []byte(testKeys), 0644))
+	assert.Nil(t, ioutil.WriteFile(bindingsFile, []byte(testBindings), 0644))
+	assert.Nil(t, ioutil.WriteFile(otherFile, []byte(strings.Replace(testKeys+"---\n"+testBindings, "namespace: application", "namespace: other", -1)), 0644))
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	// API keys are referenced by name only, so the binding in the other
+	// namespace grants the successor access as well
+	result, err := Start(dir, Key{Namespace: "application", Name: "frank"}, 32, generate.DefaultPolicy(), &privateKey.PublicKey)
+	assert.Nil(t, err)
+	assert.Equal(t, result.Changes, []Change{
+		{File: bindingsFile, Description: "added ApiKey frank-r2 to ApiKeyBinding example"},
+		{File: otherFile, Description: "added ApiKey frank-r2 to ApiKeyBinding example"},
+		{File: keysFile, Description: "added ApiKey frank-r2"},
+	})
+
+	changes, err := Remove(dir, Key{Namespace: "other", Name: "frank"})
+	assert.Nil(t, err)
+	assert.Equal(t, changes, []Change{
+		{File: bindingsFile, Description: "removed ApiKey frank from ApiKeyBinding example"},
+		{File: otherFile, Description: "removed ApiKey frank from ApiKeyBinding example"},
+		{File: otherFile, Description: "removed ApiKey frank"},
+	})
+
+	binding := readBinding(t, bindingsFile)
+	assert.Equal(t, len(binding.Spec.Keys), 2)
+	assert.Equal(t, binding.Spec.Keys[0].Name, "frank-r2")
+
+	f, err := manifest.Read(keysFile)
+	assert.Nil(t, err)
+	assert.Equal(t, len(f.Documents), 2)
+}
+
+func TestRotateInvalidBinding(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kanalictl")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	bindingsFile := filepath.Join(dir, "bindings.yaml")
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "keys.yaml"), []byte(testKeys), 0644))
+	assert.Nil(t, ioutil.WriteFile(bindingsFile, []byte(strings.Replace(testBindings, "quota: 100", "quota: lots", 1)), 0644))
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	_, err = Start(dir, Key{Namespace: "application", Name: "frank"}, 32, generate.DefaultPolicy(), &privateKey.PublicKey)
+	assert.NotNil(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), "ApiKeyBinding in "+bindingsFile+" could not be parsed: "))
+
+	_, err = Remove(dir, Key{Namespace: "application", Name: "frank"})
+	assert.NotNil(t, err)
+}
+
+func TestRotateJSON(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kanalictl")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	bindingData, err := yaml.YAMLToJSON([]byte(strings.Split(testBindings, "---\n")[0]))
+	assert.Nil(t, err)
+
+	keysFile := filepath.Join(dir, "keys.yaml")
+	bindingFile := filepath.Join(dir, "binding.json")
+	assert.Nil(t, ioutil.WriteFile(keysFile, []byte(testKeys), 0644))
+	assert.Nil(t, ioutil.WriteFile(bindingFile, bindingData, 0644))
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	frank := Key{Namespace: "application", Name: "frank"}
+
+	_, err = Start(dir, frank, 32, generate.DefaultPolicy(), &privateKey.PublicKey)
+	assert.Nil(t, err)
+
+	data, err := ioutil.ReadFile(bindingFile)
+	assert.Nil(t, err)
+	var binding spec.APIKeyBinding
+	assert.Nil(t, json.Unmarshal(data, &binding))
+	assert.Equal(t, len(binding.Spec.Keys), 3)
+	assert.Equal(t, binding.Spec.Keys[1].Name, "frank-r2")
+
+	keyData, err := yaml.YAMLToJSON([]byte(testKeys))
+	assert.Nil(t, err)
+	assert.Nil(t, os.Remove(keysFile))
+	keyFile := filepath.Join(dir, "key.json")
+	assert.Nil(t, ioutil.WriteFile(keyFile, keyData, 0644))
+
+	_, err = Start(dir, frank, 32, generate.DefaultPolicy(), &privateKey.PublicKey)
+	assert.Equal(t, err.Error(), "ApiKey application/frank is defined in the JSON file "+keyFile+", which cannot hold its successor - convert it to YAML to rotate it")
+}
+
+func readBinding(t *testing.T, file string) spec.APIKeyBinding {
+	f, err := manifest.Read(file)
+	assert.Nil(t, err)
+	assert.Equal(t, len(f.Documents), 2)
+	assert.Equal(t, f.Documents[1].Kind(), "ApiProxy")
+
+	var binding spec.APIKeyBinding
+	assert.Nil(t, yaml.Unmarshal(f.Documents[0].Data, &binding))
+	return binding
+}
@@ -0,0 +1,152 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package rsakey
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// jwk is an RSA JSON Web Key as described in RFC 7517 and RFC 7518.
+type jwk struct {
+	Kty string `json:"kty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	D   string `json:"d"`
+	P   string `json:"p"`
+	Q   string `json:"q"`
+}
+
+// jwkSet is a JSON Web Key Set as described in RFC 7517.
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+func parseJWKPublicKey(data []byte) (*rsa.PublicKey, error) {
+	key, err := parseJWK(data, false)
+	if err != nil {
+		return nil, err
+	}
+	return publicKeyFromJWK(key)
+}
+
+func parseJWKPrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	key, err := parseJWK(data, true)
+	if err != nil {
+		return nil, err
+	}
+
+	pub, err := publicKeyFromJWK(key)
+	if err != nil {
+		return nil, err
+	}
+
+	priv := &rsa.PrivateKey{PublicKey: *pub}
+	if priv.D, err = decodeJWKInt("d", key.D); err != nil {
+		return nil, err
+	}
+
+	p, err := decodeJWKInt("p", key.P)
+	if err != nil {
+		return nil, err
+	}
+	q, err := decodeJWKInt("q", key.Q)
+	if err != nil {
+		return nil, err
+	}
+	priv.Primes = []*big.Int{p, q}
+
+	if err := priv.Validate(); err != nil {
+		return nil, err
+	}
+	priv.Precompute()
+
+	return priv, nil
+}
+
+// parseJWK parses a single JWK or a JWK set containing exactly one RSA key
+// that is suitable for the requested use.
+func parseJWK(data []byte, private bool) (jwk, error) {
+	var set jwkSet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return jwk{}, fmt.Errorf("invalid JWK: %s", err.Error())
+	}
+
+	if set.Keys == nil {
+		var key jwk
+		if err := json.Unmarshal(data, &key); err != nil {
+			return jwk{}, fmt.Errorf("invalid JWK: %s", err.Error())
+		}
+		set.Keys = []jwk{key}
+	}
+
+	candidates := []jwk{}
+	for _, key := range set.Keys {
+		if key.Kty == "RSA" && (!private || len(key.D) > 0) {
+			candidates = append(candidates, key)
+		}
+	}
+
+	switch {
+	case len(candidates) == 1:
+		return candidates[0], nil
+	case len(set.Keys) == 1 && set.Keys[0].Kty != "RSA":
+		return jwk{}, fmt.Errorf("JWK has key type %s - RSA is required", set.Keys[0].Kty)
+	case len(set.Keys) == 1:
+		return jwk{}, errors.New("JWK does not contain a private key")
+	default:
+		return jwk{}, fmt.Errorf("JWK set contains %d suitable RSA keys - exactly one is required", len(candidates))
+	}
+}
+
+func publicKeyFromJWK(key jwk) (*rsa.PublicKey, error) {
+	n, err := decodeJWKInt("n", key.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := decodeJWKInt("e", key.E)
+	if err != nil {
+		return nil, err
+	}
+	if e.BitLen() > 31 {
+		return nil, errors.New("JWK parameter e is too large")
+	}
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+}
+
+func decodeJWKInt(name, value string) (*big.Int, error) {
+	if len(value) < 1 {
+		return nil, fmt.Errorf("JWK parameter %s is missing", name)
+	}
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("JWK parameter %s is not base64url encoded", name)
+	}
+	i := new(big.Int).SetBytes(data)
+	if i.Sign() <= 0 {
+		return nil, fmt.Errorf("JWK parameter %s must be positive", name)
+	}
+	return i, nil
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package rsakey
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/northwesternmutual/kanalictl/utils"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// PassphraseEnv is the environment variable the passphrase of an encrypted
+// private key is read from.
+const PassphraseEnv = "KANALICTL_RSA_PASSPHRASE"
+
+// PassphraseFunc returns the passphrase of the private key at location.
+type PassphraseFunc func(location string) ([]byte, error)
+
+// DefaultPassphrase reads the passphrase from PassphraseEnv or, if it is
+// not set, prompts for it without echoing it.
+var DefaultPassphrase = EnvOrPrompt(PassphraseEnv)
+
+// EnvOrPrompt returns a PassphraseFunc that reads the passphrase from the
+// environment variable env or, if it is not set, prompts for it on the
+// terminal without echoing it.
+func EnvOrPrompt(env string) PassphraseFunc {
+	return func(location string) ([]byte, error) {
+		if pass, ok := os.LookupEnv(env); ok {
+			return []byte(pass), nil
+		}
+
+		if !utils.IsTerminal(os.Stdin) {
+			return nil, fmt.Errorf("private key is encrypted - set %s to its passphrase", env)
+		}
+
+		fmt.Fprintf(os.Stderr, "Enter passphrase for %s: ", location)
+		pass, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return nil, err
+		}
+		return pass, nil
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package rsakey
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/youmark/pkcs8"
+)
+
+// LoadPrivateKey loads an RSA private key from a file. PKCS#1 and PKCS#8
+// PEM encoded keys, passphrase encrypted or not, and JWK files are
+// supported. passphrase is only called if the key is encrypted.
+func LoadPrivateKey(location string, passphrase PassphraseFunc) (*rsa.PrivateKey, error) {
+	if len(location) < 1 {
+		return nil, errors.New("private key not specified")
+	}
+
+	data, err := ioutil.ReadFile(location)
+	if err != nil {
+		return nil, fmt.Errorf("error reading private key %s: %s", location, err.Error())
+	}
+
+	key, err := ParsePrivateKey(data, func() ([]byte, error) {
+		if passphrase == nil {
+			return nil, errors.New("a passphrase is required")
+		}
+		return passphrase(location)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error parsing private key %s: %s", location, err.Error())
+	}
+
+	return key, nil
+}
+
+// ParsePrivateKey parses an RSA private key. passphrase is only called if
+// the key is encrypted.
+func ParsePrivateKey(data []byte, passphrase func() ([]byte, error)) (*rsa.PrivateKey, error) {
+	if isJSON(data) {
+		return parseJWKPrivateKey(data)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM encoded key found")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		der := block.Bytes
+		if x509.IsEncryptedPEMBlock(block) {
+			pass, err := passphrase()
+			if err != nil {
+				return nil, err
+			}
+			if der, err = x509.DecryptPEMBlock(block, pass); err != nil {
+				if err == x509.IncorrectPasswordError {
+					return nil, errors.New("incorrect passphrase")
+				}
+				return nil, err
+			}
+		}
+		return x509.ParsePKCS1PrivateKey(der)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return asRSAPrivateKey(key)
+	case "ENCRYPTED PRIVATE KEY":
+		pass, err := passphrase()
+		if err != nil {
+			return nil, err
+		}
+		return parseEncryptedPKCS8(block.Bytes, pass)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %s", block.Type)
+	}
+}
+
+// LoadPublicKey loads an RSA public key. location is either a file or the
+// PEM encoded key itself. PKIX and PKCS#1 PEM encoded keys, X.509
+// certificates and JWK files are supported.
+func LoadPublicKey(location string) (*rsa.PublicKey, error) {
+	if len(location) < 1 {
+		return nil, errors.New("public key not specified")
+	}
+
+	data, err := ioutil.ReadFile(location)
+	if err != nil {
+		if !strings.HasPrefix(strings.TrimSpace(location), "-----BEGIN") {
+			return nil, fmt.Errorf("error reading public key %s: %s", location, err.Error())
+		}
+		key, err := ParsePublicKey([]byte(location))
+		if err != nil {
+			return nil, fmt.Errorf("error parsing public key: %s", err.Error())
+		}
+		return key, nil
+	}
+
+	key, err := ParsePublicKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing public key %s: %s", location, err.Error())
+	}
+
+	return key, nil
+}
+
+// ParsePublicKey parses an RSA public key.
+func ParsePublicKey(data []byte) (*rsa.PublicKey, error) {
+	if isJSON(data) {
+		return parseJWKPublicKey(data)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM encoded key found")
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return asRSAPublicKey(key)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return asRSAPublicKey(cert.PublicKey)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %s", block.Type)
+	}
+}
+
+func parseEncryptedPKCS8(der, pass []byte) (key *rsa.PrivateKey, returnError error) {
+
+	// malformed ciphertext makes the underlying block cipher panic
+	defer func() {
+		if r := recover(); r != nil {
+			key, returnError = nil, errors.New("malformed encrypted private key")
+		}
+	}()
+
+	key, err := pkcs8.ParsePKCS8PrivateKeyRSA(der, pass)
+	if err != nil {
+		if err.Error() == "pkcs8: incorrect password" {
+			return nil, errors.New("incorrect passphrase")
+		}
+		return nil, err
+	}
+	return key, nil
+}
+
+func asRSAPrivateKey(key interface{}) (*rsa.PrivateKey, error) {
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%T is not an RSA private key", key)
+	}
+	return rsaKey, nil
+}
+
+func asRSAPublicKey(key interface{}) (*rsa.PublicKey, error) {
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%T is not an RSA public key", key)
+	}
+	return rsaKey, nil
+}
+
+func isJSON(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte("{"))
+}
@@ -0,0 +1,220 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package rsakey
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/youmark/pkcs8"
+)
+
+func TestParsePrivateKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.Nil(t, err)
+
+	pass := func() ([]byte, error) { return []byte("secret"), nil }
+	wrongPass := func() ([]byte, error) { return []byte("wrong"), nil }
+	noPass := func() ([]byte, error) { return nil, errors.New("a passphrase is required") }
+
+	pkcs1 := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	parsed, err := ParsePrivateKey(pkcs1, noPass)
+	assert.Nil(t, err)
+	assert.Equal(t, parsed.D, key.D)
+
+	der, err := pkcs8.ConvertPrivateKeyToPKCS8(key)
+	assert.Nil(t, err)
+	parsed, err = ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), noPass)
+	assert.Nil(t, err)
+	assert.Equal(t, parsed.D, key.D)
+
+	der, err = pkcs8.ConvertPrivateKeyToPKCS8(key, []byte("secret"))
+	assert.Nil(t, err)
+	encrypted := pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: der})
+	parsed, err = ParsePrivateKey(encrypted, pass)
+	assert.Nil(t, err)
+	assert.Equal(t, parsed.D, key.D)
+	_, err = ParsePrivateKey(encrypted, wrongPass)
+	assert.Equal(t, err.Error(), "incorrect passphrase")
+	_, err = ParsePrivateKey(encrypted, noPass)
+	assert.Equal(t, err.Error(), "a passphrase is required")
+
+	block, err := x509.EncryptPEMBlock(rand.Reader, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key), []byte("secret"), x509.PEMCipherAES256)
+	assert.Nil(t, err)
+	parsed, err = ParsePrivateKey(pem.EncodeToMemory(block), pass)
+	assert.Nil(t, err)
+	assert.Equal(t, parsed.D, key.D)
+	_, err = ParsePrivateKey(pem.EncodeToMemory(block), wrongPass)
+	assert.NotNil(t, err)
+
+	parsed, err = ParsePrivateKey(privateJWK(key), noPass)
+	assert.Nil(t, err)
+	assert.Equal(t, parsed.D, key.D)
+
+	_, err = ParsePrivateKey([]byte("not a key"), noPass)
+	assert.Equal(t, err.Error(), "no PEM encoded key found")
+	_, err = ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: []byte{}}), noPass)
+	assert.Equal(t, err.Error(), "unsupported PEM block type PUBLIC KEY")
+	_, err = ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: []byte("garbage")}), pass)
+	assert.NotNil(t, err)
+}
+
+func TestParsePublicKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.Nil(t, err)
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	assert.Nil(t, err)
+	parsed, err := ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	assert.Nil(t, err)
+	assert.Equal(t, parsed, &key.PublicKey)
+
+	der, err = asn1.Marshal(struct {
+		N *big.Int
+		E int
+	}{key.N, key.E})
+	assert.Nil(t, err)
+	parsed, err = ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: der}))
+	assert.Nil(t, err)
+	assert.Equal(t, parsed, &key.PublicKey)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "kanali"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err = x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.Nil(t, err)
+	parsed, err = ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	assert.Nil(t, err)
+	assert.Equal(t, parsed, &key.PublicKey)
+
+	parsed, err = ParsePublicKey(publicJWK(key))
+	assert.Nil(t, err)
+	assert.Equal(t, parsed, &key.PublicKey)
+
+	set, err := json.Marshal(map[string][]json.RawMessage{"keys": {
+		json.RawMessage(`{"kty":"EC","crv":"P-256"}`),
+		json.RawMessage(publicJWK(key)),
+	}})
+	assert.Nil(t, err)
+	parsed, err = ParsePublicKey(set)
+	assert.Nil(t, err)
+	assert.Equal(t, parsed, &key.PublicKey)
+
+	_, err = ParsePublicKey([]byte(`{"kty":"EC","crv":"P-256"}`))
+	assert.Equal(t, err.Error(), "JWK has key type EC - RSA is required")
+	_, err = ParsePublicKey([]byte(`{"kty":"RSA","e":"AQAB"}`))
+	assert.Equal(t, err.Error(), "JWK parameter n is missing")
+	_, err = ParsePublicKey([]byte("not a key"))
+	assert.Equal(t, err.Error(), "no PEM encoded key found")
+	_, err = ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+	assert.Equal(t, err.Error(), "unsupported PEM block type RSA PRIVATE KEY")
+}
+
+func TestLoadKeys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rsakey")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.Nil(t, err)
+
+	der, err := pkcs8.ConvertPrivateKeyToPKCS8(key, []byte("secret"))
+	assert.Nil(t, err)
+	privateFile := filepath.Join(dir, "private.pem")
+	assert.Nil(t, ioutil.WriteFile(privateFile, pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: der}), 0600))
+
+	der, err = x509.MarshalPKIXPublicKey(&key.PublicKey)
+	assert.Nil(t, err)
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	publicFile := filepath.Join(dir, "public.pem")
+	assert.Nil(t, ioutil.WriteFile(publicFile, publicPEM, 0644))
+
+	parsed, err := LoadPrivateKey(privateFile, func(location string) ([]byte, error) {
+		assert.Equal(t, location, privateFile)
+		return []byte("secret"), nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, parsed.D, key.D)
+
+	_, err = LoadPrivateKey(privateFile, nil)
+	assert.Equal(t, err.Error(), "error parsing private key "+privateFile+": a passphrase is required")
+
+	os.Setenv("KANALICTL_TEST_PASSPHRASE", "secret")
+	defer os.Unsetenv("KANALICTL_TEST_PASSPHRASE")
+	_, err = LoadPrivateKey(privateFile, EnvOrPrompt("KANALICTL_TEST_PASSPHRASE"))
+	assert.Nil(t, err)
+
+	_, err = LoadPrivateKey("", nil)
+	assert.Equal(t, err.Error(), "private key not specified")
+	_, err = LoadPrivateKey(filepath.Join(dir, "missing.pem"), nil)
+	assert.NotNil(t, err)
+
+	pub, err := LoadPublicKey(publicFile)
+	assert.Nil(t, err)
+	assert.Equal(t, pub, &key.PublicKey)
+
+	pub, err = LoadPublicKey(string(publicPEM))
+	assert.Nil(t, err)
+	assert.Equal(t, pub, &key.PublicKey)
+
+	_, err = LoadPublicKey("")
+	assert.Equal(t, err.Error(), "public key not specified")
+	_, err = LoadPublicKey(filepath.Join(dir, "missing.pem"))
+	assert.Contains(t, err.Error(), "error reading public key")
+}
+
+func publicJWK(key *rsa.PrivateKey) []byte {
+	data, _ := json.Marshal(map[string]string{
+		"kty": "RSA",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	})
+	return data
+}
+
+func privateJWK(key *rsa.PrivateKey) []byte {
+	data, _ := json.Marshal(map[string]string{
+		"kty": "RSA",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		"d":   base64.RawURLEncoding.EncodeToString(key.D.Bytes()),
+		"p":   base64.RawURLEncoding.EncodeToString(key.Primes[0].Bytes()),
+		"q":   base64.RawURLEncoding.EncodeToString(key.Primes[1].Bytes()),
+	})
+	return data
+}
@@ -23,9 +23,12 @@ package utils
 import (
 	"bytes"
 	"os/exec"
+	"syscall"
 )
 
-// Execute performs an os command
+// Execute performs an os command. On success it returns everything the
+// command wrote, including warnings written to stderr. On failure it
+// returns what the command wrote to stderr and its exit code.
 func Execute(name string, arg ...string) (string, int) {
 
 	create := exec.Command(name, arg...)
@@ -37,10 +40,15 @@ func Execute(name string, arg ...string) (string, int) {
 	create.Stderr = &stderr
 
 	err := create.Run()
-	if err != nil {
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.ExitStatus() > 0 {
+			return stderr.String(), status.ExitStatus()
+		}
 		return stderr.String(), 1
+	} else if err != nil {
+		return err.Error(), 1
 	}
 
-	return out.String(), 0
+	return out.String() + stderr.String(), 0
 
 }
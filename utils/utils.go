@@ -21,6 +21,7 @@
 package utils
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/spf13/pflag"
@@ -55,3 +56,23 @@ func JoinArray(arr []string) string {
 	return strings.Join(arr, ",")
 
 }
+
+// ParseKeyValues parses a list of key=value pairs into a map
+func ParseKeyValues(pairs []string) (map[string]string, error) {
+
+	if len(pairs) < 1 {
+		return nil, nil
+	}
+
+	m := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || len(kv[0]) < 1 {
+			return nil, fmt.Errorf("%q must be of the form key=value", pair)
+		}
+		m[kv[0]] = kv[1]
+	}
+
+	return m, nil
+
+}
@@ -32,13 +32,19 @@ import (
 
 // ValidateAPIKeyBinding performs validation on an APIKeyBinding
 func ValidateAPIKeyBinding(binding spec.APIKeyBinding, client utils.HTTPClient, host string) error {
+	return ValidateAPIKeyBindingWith(binding, Cluster(client, host))
+}
+
+// ValidateAPIKeyBindingWith performs validation on an APIKeyBinding,
+// requiring its proxy name to be unique among the given resources
+func ValidateAPIKeyBindingWith(binding spec.APIKeyBinding, resources Resources) error {
 	if binding.Spec.APIProxyName == "" {
 		return errors.New("proxy name must be defined")
 	}
 
 	// check to make sure that there are no other bindings
 	// with the same proxy name
-	if err := checkUniqueProxyName(binding.ObjectMeta.Name, binding.ObjectMeta.Namespace, binding.Spec.APIProxyName, client, host); err != nil {
+	if err := checkUniqueProxyName(binding.ObjectMeta.Name, binding.ObjectMeta.Namespace, binding.Spec.APIProxyName, resources); err != nil {
 		return err
 	}
 
@@ -125,14 +131,14 @@ func validateRule(rule spec.Rule) error {
 
 }
 
-func checkUniqueProxyName(name, namespace, proxy string, client utils.HTTPClient, host string) error {
+func checkUniqueProxyName(name, namespace, proxy string, resources Resources) error {
 
-	bindingList, err := retrieveBindingList(client, host)
+	bindings, err := resources.APIKeyBindings()
 	if err != nil {
 		return err
 	}
 
-	for _, binding := range bindingList.Bindings {
+	for _, binding := range bindings {
 		if binding.Spec.APIProxyName == proxy {
 			if binding.ObjectMeta.Name != name || binding.ObjectMeta.Namespace != namespace {
 				return fmt.Errorf("The ApiKeyBinding %s in namespace %s has the same path. Paths must be unique", binding.ObjectMeta.Name, binding.ObjectMeta.Namespace)
@@ -39,6 +39,12 @@ const (
 
 // ValidateAPIProxy performs validation on an APIProxy
 func ValidateAPIProxy(proxy spec.APIProxy, client utils.HTTPClient, host string) error {
+	return ValidateAPIProxyWith(proxy, Cluster(client, host))
+}
+
+// ValidateAPIProxyWith performs validation on an APIProxy, requiring its
+// path to be unique among the given resources
+func ValidateAPIProxyWith(proxy spec.APIProxy, resources Resources) error {
 
 	// is path defined
 	if err := checkIfPathIsValid(proxy.Spec.Path); err != nil {
@@ -46,7 +52,7 @@ func ValidateAPIProxy(proxy spec.APIProxy, client utils.HTTPClient, host string)
 	}
 
 	// is path unique
-	if err := checkUniquePath(proxy.ObjectMeta.Name, proxy.ObjectMeta.Namespace, proxy.Spec.Path, client, host); err != nil {
+	if err := checkUniquePath(proxy.ObjectMeta.Name, proxy.ObjectMeta.Namespace, proxy.Spec.Path, resources); err != nil {
 		return err
 	}
 
@@ -100,14 +106,14 @@ func validatePlugins(plugins []spec.Plugin) error {
 
 }
 
-func checkUniquePath(name, namespace, path string, client utils.HTTPClient, host string) error {
+func checkUniquePath(name, namespace, path string, resources Resources) error {
 
-	proxyList, err := retrieveProxyList(client, host)
+	proxies, err := resources.APIProxies()
 	if err != nil {
 		return err
 	}
 
-	for _, proxy := range proxyList.Proxies {
+	for _, proxy := range proxies {
 		if proxy.Spec.Path == path {
 			if proxy.ObjectMeta.Name != name || proxy.ObjectMeta.Namespace != namespace {
 				return fmt.Errorf("The ApiProxy %s in namespace %s has the same path. Paths must be unique", proxy.ObjectMeta.Name, proxy.ObjectMeta.Namespace)
@@ -0,0 +1,78 @@
+// Copyright (c) 2017 Northwestern Mutual.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package validation
+
+import (
+	"github.com/northwesternmutual/kanali/spec"
+	"github.com/northwesternmutual/kanalictl/utils"
+)
+
+// Resources are the ApiProxies and ApiKeyBindings that paths and proxy
+// names must be unique among.
+type Resources interface {
+	APIProxies() ([]spec.APIProxy, error)
+	APIKeyBindings() ([]spec.APIKeyBinding, error)
+}
+
+type clusterResources struct {
+	client utils.HTTPClient
+	host   string
+}
+
+type localResources struct {
+	proxies  []spec.APIProxy
+	bindings []spec.APIKeyBinding
+}
+
+// Cluster returns the resources in the cluster whose API server is at host.
+func Cluster(client utils.HTTPClient, host string) Resources {
+	return clusterResources{client: client, host: host}
+}
+
+// Local returns the given resources, such as those read from local
+// configuration files.
+func Local(proxies []spec.APIProxy, bindings []spec.APIKeyBinding) Resources {
+	return localResources{proxies: proxies, bindings: bindings}
+}
+
+func (r clusterResources) APIProxies() ([]spec.APIProxy, error) {
+	list, err := retrieveProxyList(r.client, r.host)
+	if err != nil {
+		return nil, err
+	}
+	return list.Proxies, nil
+}
+
+func (r clusterResources) APIKeyBindings() ([]spec.APIKeyBinding, error) {
+	list, err := retrieveBindingList(r.client, r.host)
+	if err != nil {
+		return nil, err
+	}
+	return list.Bindings, nil
+}
+
+func (r localResources) APIProxies() ([]spec.APIProxy, error) {
+	return r.proxies, nil
+}
+
+func (r localResources) APIKeyBindings() ([]spec.APIKeyBinding, error) {
+	return r.bindings, nil
+}